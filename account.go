@@ -22,14 +22,14 @@ func (c accountCommand) Execute(_ []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if c.Args.Name != "" {
-		return c.createAccount(ctx)
-	}
-
 	if c.Remove {
 		return c.remove(ctx)
 	}
 
+	if c.Args.Name != "" {
+		return c.createAccount(ctx)
+	}
+
 	return c.list(ctx)
 }
 
@@ -59,7 +59,63 @@ func (c accountCommand) list(ctx context.Context) error {
 }
 
 func (c accountCommand) remove(ctx context.Context) error {
-	return api.DeleteAccount(ctx, c.Args.Name)
+	if c.Args.Name == "" {
+		return errors.New("you need to provide identifier of account")
+	}
+
+	projects, err := api.GetProjects(ctx, &c.Args.Name)
+	if err != nil {
+		return err
+	}
+
+	envs, err := api.GetEnvironments(ctx, &c.Args.Name)
+	if err != nil {
+		return err
+	}
+
+	flagCount := 0
+	for _, project := range projects {
+		flags, err := api.GetFlags(ctx, c.Args.Name, project.Identifier)
+		if err != nil {
+			return err
+		}
+		flagCount += len(flags)
+		for _, flag := range flags {
+			if flag.Permanent {
+				return fmt.Errorf("account %s contains permanent flag %s in project %s and cannot be removed",
+					c.Args.Name, flag.Identifier, project.Identifier)
+			}
+		}
+	}
+
+	// API does not list keys so their number cannot be shown
+	label := fmt.Sprintf("Remove account %s with %d projects, %d environments, %d flags and all API keys (count not available)",
+		c.Args.Name, len(projects), len(envs), flagCount)
+	if dryRun("DELETE", "account "+c.Args.Name, nil) {
+		return nil
+	}
+
+	production := false
+	for _, env := range envs {
+		production = production || env.Production
+	}
+
+	// production data is removed with account so user has to type account identifier
+	if production {
+		err = confirmTyped(fmt.Sprintf("%s including production data, type %s to confirm", label, c.Args.Name),
+			c.Args.Name)
+	} else {
+		err = confirm(label)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = api.DeleteAccount(ctx, c.Args.Name); err != nil {
+		return err
+	}
+	fmt.Printf("Account %s successfully removed\n", c.Args.Name)
+	return nil
 }
 
 func (c accountCommand) createAccount(ctx context.Context) error {
@@ -70,6 +126,10 @@ func (c accountCommand) createAccount(ctx context.Context) error {
 		Name: c.Args.Name,
 	}
 
+	if dryRun("POST", "account", body) {
+		return nil
+	}
+
 	response, err := api.CreateAccount(ctx, &body)
 	if err != nil {
 		return err
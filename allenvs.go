@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type envResult struct {
+	Environment string         `json:"environment"`
+	Values      map[string]any `json:"values,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// allEnvs evaluates flags for the same target in every environment using
+// API key stored for each environment by key --set-env
+func (c evaluateCommand) allEnvs(target map[string]any) error {
+	if len(c.Args.Identifiers) == 0 {
+		return errors.New("provide flag identifiers to evaluate")
+	}
+
+	if c.Project == "" {
+		return errors.New("-p or --project flag is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	envs, err := api.GetEnvironments(ctx, &c.Account)
+	if err != nil {
+		return err
+	}
+
+	results := make([]envResult, len(envs))
+	var wg sync.WaitGroup
+	for i, env := range envs {
+		results[i].Environment = env.Identifier
+		apiKey := os.Getenv(apiKeyEnvName(c.Project, env.Identifier))
+		if apiKey == "" {
+			results[i].Error = fmt.Sprintf("no API key, set %s or run key --set-env", apiKeyEnvName(c.Project, env.Identifier))
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, apiKey string) {
+			defer wg.Done()
+			values, err := remoteEvaluator(apiKey, c.Args.Identifiers...)(ctx, target)
+			results[i].Values = values
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, apiKey)
+	}
+	wg.Wait()
+
+	return c.printEnvs(results)
+}
+
+func (c evaluateCommand) printEnvs(results []envResult) error {
+	if c.Output == "json" {
+		fmt.Print(jsonFormatter("", "  ", results))
+		return nil
+	}
+
+	header := []string{"Flag"}
+	for _, result := range results {
+		header = append(header, result.Environment)
+	}
+
+	var rows [][]string
+	for _, flag := range c.Args.Identifiers {
+		row := []string{flag}
+		for _, result := range results {
+			value := result.Error
+			if v, ok := result.Values[flag]; ok {
+				value = compactJSON(v)
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+
+	return printMatrix(c.Output, header, rows)
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/ast"
+	exprparser "github.com/antonmedv/expr/parser"
+	"github.com/simpleflags/evaluation"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ruleInvalid     = "invalid"
+	ruleShadowed    = "shadowed"
+	ruleAlwaysTrue  = "always-true"
+	ruleAlwaysFalse = "always-false"
+)
+
+type ruleProblem struct {
+	Index   int
+	Kind    string
+	Message string
+}
+
+// conjunction is a set of canonical expressions joined with &&
+type conjunction map[string]ast.Node
+
+// analyzedRule is expression in disjunctive form, rule matches when any of conjunctions is true
+type analyzedRule []conjunction
+
+// analyzeRules reports rules which can never be reached because earlier rule matches first
+// and rules which are always true or always false regardless of target
+func analyzeRules(rules []evaluation.Rule) []ruleProblem {
+	var problems []ruleProblem
+	analyzed := make([]analyzedRule, len(rules))
+	alwaysTrue := -1
+	for i, rule := range rules {
+		if alwaysTrue >= 0 {
+			problems = append(problems, ruleProblem{
+				Index:   i,
+				Kind:    ruleShadowed,
+				Message: fmt.Sprintf("shadowed by rule %d which is always true", alwaysTrue+1),
+			})
+			continue
+		}
+
+		tree, err := exprparser.Parse(rule.Expression)
+		if err != nil {
+			problems = append(problems, ruleProblem{
+				Index:   i,
+				Kind:    ruleInvalid,
+				Message: err.Error(),
+			})
+			continue
+		}
+		analyzed[i] = disjunctions(tree.Node)
+
+		switch constant(rule.Expression, tree.Node, analyzed[i]) {
+		case "true":
+			alwaysTrue = i
+			problems = append(problems, ruleProblem{
+				Index:   i,
+				Kind:    ruleAlwaysTrue,
+				Message: "expression is always true, following rules are never evaluated",
+			})
+		case "false":
+			analyzed[i] = nil
+			problems = append(problems, ruleProblem{
+				Index:   i,
+				Kind:    ruleAlwaysFalse,
+				Message: "expression is always false",
+			})
+			continue
+		}
+
+		for j := 0; j < i; j++ {
+			if analyzed[j] != nil && covers(analyzed[j], analyzed[i]) {
+				problems = append(problems, ruleProblem{
+					Index:   i,
+					Kind:    ruleShadowed,
+					Message: fmt.Sprintf("shadowed by rule %d (%s)", j+1, rules[j].Expression),
+				})
+				break
+			}
+		}
+	}
+	return problems
+}
+
+// covers reports whether earlier rule is true whenever later rule is true
+func covers(earlier, later analyzedRule) bool {
+	if len(later) == 0 {
+		return false
+	}
+	for _, l := range later {
+		covered := false
+		for _, e := range earlier {
+			if subset(e, l) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func subset(a, b conjunction) bool {
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// constant returns "true" or "false" when expression does not depend on target
+func constant(expression string, node ast.Node, rule analyzedRule) string {
+	if !hasIdentifiers(node) {
+		value, err := expr.Eval(expression, nil)
+		if b, ok := value.(bool); err == nil && ok {
+			return strconv.FormatBool(b)
+		}
+		return ""
+	}
+
+	contradictions := 0
+	single := make(map[string]bool)
+	for _, conj := range rule {
+		if contradicts(conj) {
+			contradictions++
+			continue
+		}
+		if tautology(conj) {
+			return "true"
+		}
+		if len(conj) == 1 {
+			for key := range conj {
+				single[key] = true
+			}
+		}
+	}
+	// x || !x
+	for key := range single {
+		if single["(! "+key+")"] {
+			return "true"
+		}
+	}
+	if contradictions == len(rule) {
+		return "false"
+	}
+	return ""
+}
+
+// contradicts reports whether conjunction contains x and !x or x == a and x == b
+func contradicts(conj conjunction) bool {
+	equals := make(map[string]string)
+	for key, node := range conj {
+		if _, ok := conj["(! "+key+")"]; ok {
+			return true
+		}
+		if b, ok := node.(*ast.BoolNode); ok && !b.Value {
+			return true
+		}
+		bin, ok := node.(*ast.BinaryNode)
+		if !ok || bin.Operator != "==" {
+			continue
+		}
+		left, right := bin.Left, bin.Right
+		if isLiteral(left) {
+			left, right = right, left
+		}
+		if !isLiteral(right) || isLiteral(left) {
+			continue
+		}
+		l, r := nodeString(left), nodeString(right)
+		if prev, ok := equals[l]; ok && prev != r {
+			return true
+		}
+		equals[l] = r
+	}
+	return false
+}
+
+func tautology(conj conjunction) bool {
+	for _, node := range conj {
+		if b, ok := node.(*ast.BoolNode); !ok || !b.Value {
+			return false
+		}
+	}
+	return len(conj) > 0
+}
+
+// disjunctions splits expression on || and each part on && without distributing
+func disjunctions(node ast.Node) analyzedRule {
+	if bin, ok := node.(*ast.BinaryNode); ok && (bin.Operator == "||" || bin.Operator == "or") {
+		return append(disjunctions(bin.Left), disjunctions(bin.Right)...)
+	}
+	conj := make(conjunction)
+	conjunctions(node, conj)
+	return analyzedRule{conj}
+}
+
+func conjunctions(node ast.Node, conj conjunction) {
+	if bin, ok := node.(*ast.BinaryNode); ok && (bin.Operator == "&&" || bin.Operator == "and") {
+		conjunctions(bin.Left, conj)
+		conjunctions(bin.Right, conj)
+		return
+	}
+	conj[nodeString(node)] = node
+}
+
+func isLiteral(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.NilNode, *ast.IntegerNode, *ast.FloatNode, *ast.BoolNode, *ast.StringNode, *ast.ConstantNode:
+		return true
+	}
+	return false
+}
+
+type identifierVisitor struct {
+	found bool
+}
+
+func (v *identifierVisitor) Enter(*ast.Node) {}
+
+func (v *identifierVisitor) Exit(node *ast.Node) {
+	switch (*node).(type) {
+	case *ast.IdentifierNode, *ast.PointerNode:
+		v.found = true
+	}
+}
+
+func hasIdentifiers(node ast.Node) bool {
+	v := &identifierVisitor{}
+	ast.Walk(&node, v)
+	return v.found
+}
+
+// nodeString returns canonical representation of expression node,
+// operator aliases are normalized and operands of == and != are ordered
+func nodeString(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.NilNode:
+		return "nil"
+	case *ast.IdentifierNode:
+		return n.Value
+	case *ast.IntegerNode:
+		return strconv.Itoa(n.Value)
+	case *ast.FloatNode:
+		return strconv.FormatFloat(n.Value, 'g', -1, 64)
+	case *ast.BoolNode:
+		return strconv.FormatBool(n.Value)
+	case *ast.StringNode:
+		return strconv.Quote(n.Value)
+	case *ast.ConstantNode:
+		return fmt.Sprintf("%#v", n.Value)
+	case *ast.UnaryNode:
+		operator := n.Operator
+		if operator == "not" {
+			operator = "!"
+		}
+		return "(" + operator + " " + nodeString(n.Node) + ")"
+	case *ast.BinaryNode:
+		operator := n.Operator
+		switch operator {
+		case "and":
+			operator = "&&"
+		case "or":
+			operator = "||"
+		}
+		operands := []string{nodeString(n.Left), nodeString(n.Right)}
+		if operator == "==" || operator == "!=" {
+			sort.Strings(operands)
+		}
+		return "(" + operands[0] + " " + operator + " " + operands[1] + ")"
+	case *ast.MatchesNode:
+		return "(" + nodeString(n.Left) + " matches " + nodeString(n.Right) + ")"
+	case *ast.PropertyNode:
+		return nodeString(n.Node) + "." + n.Property
+	case *ast.IndexNode:
+		return nodeString(n.Node) + "[" + nodeString(n.Index) + "]"
+	case *ast.SliceNode:
+		from, to := "", ""
+		if n.From != nil {
+			from = nodeString(n.From)
+		}
+		if n.To != nil {
+			to = nodeString(n.To)
+		}
+		return nodeString(n.Node) + "[" + from + ":" + to + "]"
+	case *ast.MethodNode:
+		return nodeString(n.Node) + "." + n.Method + "(" + nodeStrings(n.Arguments) + ")"
+	case *ast.FunctionNode:
+		return n.Name + "(" + nodeStrings(n.Arguments) + ")"
+	case *ast.BuiltinNode:
+		return n.Name + "(" + nodeStrings(n.Arguments) + ")"
+	case *ast.ClosureNode:
+		return "{" + nodeString(n.Node) + "}"
+	case *ast.PointerNode:
+		return "#"
+	case *ast.ConditionalNode:
+		return "(" + nodeString(n.Cond) + " ? " + nodeString(n.Exp1) + " : " + nodeString(n.Exp2) + ")"
+	case *ast.ArrayNode:
+		return "[" + nodeStrings(n.Nodes) + "]"
+	case *ast.MapNode:
+		return "{" + nodeStrings(n.Pairs) + "}"
+	case *ast.PairNode:
+		return nodeString(n.Key) + ": " + nodeString(n.Value)
+	}
+	return fmt.Sprintf("%T", node)
+}
+
+func nodeStrings(nodes []ast.Node) string {
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = nodeString(node)
+	}
+	return strings.Join(parts, ", ")
+}
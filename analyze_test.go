@@ -0,0 +1,143 @@
+package main
+
+import (
+	exprparser "github.com/antonmedv/expr/parser"
+	"github.com/simpleflags/evaluation"
+	"testing"
+)
+
+func parseRule(t *testing.T, expression string) analyzedRule {
+	t.Helper()
+	tree, err := exprparser.Parse(expression)
+	if err != nil {
+		t.Fatalf("parse %q: %v", expression, err)
+	}
+	return disjunctions(tree.Node)
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		earlier, later string
+		want           bool
+	}{
+		{`target.country == 'US'`, `target.country == 'US' && target.beta`, true},
+		{`target.country == 'US'`, `'US' == target.country`, true},
+		{`target.country == 'US' || target.country == 'DE'`, `target.country == 'DE' and target.beta`, true},
+		{`target.country == 'US' && target.beta`, `target.country == 'US'`, false},
+		{`target.country == 'US'`, `target.country == 'DE'`, false},
+		{`target.country == 'US'`, `target.country == 'US' || target.beta`, false},
+	}
+	for _, tt := range tests {
+		got := covers(parseRule(t, tt.earlier), parseRule(t, tt.later))
+		if got != tt.want {
+			t.Errorf("covers(%q, %q) = %v, want %v", tt.earlier, tt.later, got, tt.want)
+		}
+	}
+}
+
+func TestContradicts(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{`target.beta && !target.beta`, true},
+		{`target.beta and not target.beta`, true},
+		{`target.country == 'US' && target.country == 'DE'`, true},
+		{`target.country == 'US' && 'DE' == target.country`, true},
+		{`target.beta && false`, true},
+		{`target.country == 'US' && target.country == 'US'`, false},
+		{`target.country == 'US' && target.plan == 'pro'`, false},
+		{`target.beta`, false},
+	}
+	for _, tt := range tests {
+		rule := parseRule(t, tt.expression)
+		if len(rule) != 1 {
+			t.Fatalf("%q: expected single conjunction, got %d", tt.expression, len(rule))
+		}
+		if got := contradicts(rule[0]); got != tt.want {
+			t.Errorf("contradicts(%q) = %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestConstant(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{`true`, "true"},
+		{`1 == 1`, "true"},
+		{`1 > 2`, "false"},
+		{`target.beta || !target.beta`, "true"},
+		{`target.beta || true`, "true"},
+		{`target.beta && !target.beta`, "false"},
+		{`target.country == 'US' && target.country == 'DE'`, "false"},
+		{`(target.beta && !target.beta) || target.plan == 'pro'`, ""},
+		{`target.country == 'US'`, ""},
+		{`"text"`, ""},
+	}
+	for _, tt := range tests {
+		tree, err := exprparser.Parse(tt.expression)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.expression, err)
+		}
+		if got := constant(tt.expression, tree.Node, disjunctions(tree.Node)); got != tt.want {
+			t.Errorf("constant(%q) = %q, want %q", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		want  []ruleProblem
+	}{
+		{
+			name:  "later rule made unreachable by earlier one",
+			rules: []string{`target.country == 'US'`, `target.country == 'US' && target.beta`},
+			want:  []ruleProblem{{Index: 1, Kind: ruleShadowed}},
+		},
+		{
+			name:  "independent rules",
+			rules: []string{`target.country == 'US'`, `target.country == 'DE'`},
+		},
+		{
+			name:  "always true rule shadows everything after it",
+			rules: []string{`true`, `target.beta`, `target.country == 'US'`},
+			want: []ruleProblem{
+				{Index: 0, Kind: ruleAlwaysTrue},
+				{Index: 1, Kind: ruleShadowed},
+				{Index: 2, Kind: ruleShadowed},
+			},
+		},
+		{
+			name:  "always false rule does not shadow",
+			rules: []string{`target.beta && !target.beta`, `target.beta`},
+			want:  []ruleProblem{{Index: 0, Kind: ruleAlwaysFalse}},
+		},
+		{
+			name:  "invalid expression",
+			rules: []string{`target.country ==`},
+			want:  []ruleProblem{{Index: 0, Kind: ruleInvalid}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := make([]evaluation.Rule, len(tt.rules))
+			for i, expression := range tt.rules {
+				rules[i] = evaluation.Rule{Expression: expression, Value: true}
+			}
+			got := analyzeRules(rules)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d problems %v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if got[i].Index != tt.want[i].Index || got[i].Kind != tt.want[i].Kind {
+					t.Errorf("problem %d = %d %s, want %d %s",
+						i, got[i].Index, got[i].Kind, tt.want[i].Index, tt.want[i].Kind)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/simpleflags/services/pkg/api/client"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+type evaluator func(ctx context.Context, target map[string]any) (map[string]any, error)
+
+type batchResult struct {
+	Name   string         `json:"name,omitempty"`
+	Target map[string]any `json:"target"`
+	Values map[string]any `json:"values,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// evaluator returns local evaluator when snapshot is provided, otherwise remote one
+func (c evaluateCommand) evaluator() (evaluator, error) {
+	if c.Snapshot != "" {
+		s, err := loadSnapshot(c.Snapshot)
+		if err != nil {
+			return nil, err
+		}
+		return func(_ context.Context, target map[string]any) (map[string]any, error) {
+			evaluations, err := s.evaluate(target, c.Args.Identifiers...)
+			if err != nil {
+				return nil, err
+			}
+			return evaluationValues(evaluations), nil
+		}, nil
+	}
+
+	return remoteEvaluator(c.apiKey(), c.Args.Identifiers...), nil
+}
+
+// apiKey returns API key stored for selected environment or default one
+func (c evaluateCommand) apiKey() string {
+	return envAPIKey(c.Project, c.Env)
+}
+
+// envAPIKey returns key stored for environment of project by key command, SF_API_KEY otherwise
+func envAPIKey(project, env string) string {
+	if project != "" && env != "" {
+		if apiKey := os.Getenv(apiKeyEnvName(project, env)); apiKey != "" {
+			return apiKey
+		}
+	}
+	return os.Getenv("SF_API_KEY")
+}
+
+func remoteEvaluator(apiKey string, identifiers ...string) evaluator {
+	clientAPI := client.New(apiKey)
+	return func(ctx context.Context, target map[string]any) (map[string]any, error) {
+		evaluations, err := clientAPI.Evaluate(ctx, nil, nil, nil, target, identifiers...)
+		if err != nil {
+			return nil, err
+		}
+		return evaluationValues(evaluations), nil
+	}
+}
+
+// batch evaluates flags for every target from file
+func (c evaluateCommand) batch() error {
+	targets, err := readTargets(c.Targets)
+	if err != nil {
+		return err
+	}
+	return c.evaluateAll(nil, targets)
+}
+
+// evaluateAll evaluates flags for every target using bounded number of workers,
+// names are used as row labels when provided
+func (c evaluateCommand) evaluateAll(names []string, targets []map[string]any) error {
+	evaluate, err := c.evaluator()
+	if err != nil {
+		return err
+	}
+
+	workers := c.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]batchResult, len(targets))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				values, err := evaluate(ctx, targets[i])
+				cancel()
+				results[i] = batchResult{Target: targets[i], Values: values}
+				if i < len(names) {
+					results[i].Name = names[i]
+				}
+				if err != nil {
+					results[i].Error = err.Error()
+				}
+			}
+		}()
+	}
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err = c.printBatch(results); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("evaluation failed for %d of %d targets", failed, len(results))
+	}
+	return nil
+}
+
+func (c evaluateCommand) printBatch(results []batchResult) error {
+	if c.Output == "json" {
+		fmt.Print(jsonFormatter("", "  ", results))
+		return nil
+	}
+
+	flags := c.Args.Identifiers
+	if len(flags) == 0 {
+		seen := make(map[string]bool)
+		for _, result := range results {
+			for flag := range result.Values {
+				if !seen[flag] {
+					seen[flag] = true
+					flags = append(flags, flag)
+				}
+			}
+		}
+		sort.Strings(flags)
+	}
+
+	header := []string{"Target"}
+	header = append(header, flags...)
+	header = append(header, "Error")
+
+	rows := make([][]string, len(results))
+	for i, result := range results {
+		row := []string{targetLabel(i, result)}
+		for _, flag := range flags {
+			value := ""
+			if v, ok := result.Values[flag]; ok {
+				value = compactJSON(v)
+			}
+			row = append(row, value)
+		}
+		rows[i] = append(row, result.Error)
+	}
+
+	return printMatrix(c.Output, header, rows)
+}
+
+// printMatrix prints rows as csv or as a table
+func printMatrix(format string, header []string, rows [][]string) error {
+	if format == "csv" {
+		w := csv.NewWriter(os.Stdout)
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		return w.WriteAll(rows)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	columns := table.Row{}
+	for _, h := range header {
+		columns = append(columns, h)
+	}
+	t.AppendHeader(columns)
+	for _, row := range rows {
+		item := table.Row{}
+		for _, val := range row {
+			item = append(item, val)
+		}
+		t.AppendRow(item)
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+	return nil
+}
+
+// targetLabel returns name, target identifier or row number when target has neither
+func targetLabel(i int, result batchResult) string {
+	if result.Name != "" {
+		return result.Name
+	}
+	if identifier, ok := result.Target["identifier"]; ok {
+		return fmt.Sprint(identifier)
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
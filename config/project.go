@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// ProjectFile is default name of config file kept in the repository
+const ProjectFile = ".simpleflags.json"
+
+type LintConfig struct {
+	// Rules enables or disables lint rules by name, rules not listed are enabled
+	Rules map[string]bool `json:"rules,omitempty"`
+}
+
+type PullConfig struct {
+	// Interval in seconds, overrides --interval when set
+	Interval int `json:"interval,omitempty"`
+	// Flags overrides --flags when set
+	Flags []string `json:"flags,omitempty"`
+}
+
+type Project struct {
+	Lint    LintConfig                `json:"lint"`
+	Pull    *PullConfig               `json:"pull,omitempty"`
+	Targets map[string]map[string]any `json:"targets,omitempty"`
+
+	// raw keeps all keys of the file so keys not known to this version are written back
+	raw map[string]json.RawMessage
+}
+
+// LoadProject reads project config file, missing file results in empty config
+func LoadProject(file string) (*Project, error) {
+	project := &Project{}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return project, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, project); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &project.raw); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// SaveProject writes project config file, keys not known to Project are kept as they were read
+func SaveProject(file string, project *Project) error {
+	data, err := json.Marshal(project)
+	if err != nil {
+		return err
+	}
+
+	var known map[string]json.RawMessage
+	if err = json.Unmarshal(data, &known); err != nil {
+		return err
+	}
+	// empty lint section is not written unless file already had one
+	if _, ok := project.raw["lint"]; !ok && len(project.Lint.Rules) == 0 {
+		delete(known, "lint")
+	}
+
+	content := make(map[string]json.RawMessage, len(project.raw)+len(known))
+	for key, value := range project.raw {
+		content[key] = value
+	}
+	for _, key := range []string{"lint", "pull", "targets"} {
+		delete(content, key)
+		if value, ok := known[key]; ok {
+			content[key] = value
+		}
+	}
+
+	if data, err = json.MarshalIndent(content, "", "  "); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
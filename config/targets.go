@@ -0,0 +1,103 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+const targetsFile = "targets.json"
+
+// LoadTargets returns saved targets from simpleflags directory and from project
+// config file, targets from project config file take precedence
+func LoadTargets() (map[string]map[string]any, error) {
+	targets, err := loadUserTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := LoadProject(ProjectFile)
+	if err != nil {
+		return nil, err
+	}
+	for name, target := range project.Targets {
+		targets[name] = target
+	}
+	return targets, nil
+}
+
+// SaveTarget stores target under name in simpleflags directory or in project config file
+func SaveTarget(name string, target map[string]any, local bool) error {
+	if local {
+		project, err := LoadProject(ProjectFile)
+		if err != nil {
+			return err
+		}
+		if project.Targets == nil {
+			project.Targets = make(map[string]map[string]any)
+		}
+		project.Targets[name] = target
+		return SaveProject(ProjectFile, project)
+	}
+
+	targets, err := loadUserTargets()
+	if err != nil {
+		return err
+	}
+	targets[name] = target
+	return saveUserTargets(targets)
+}
+
+// RemoveTarget removes target from simpleflags directory or from project config file
+func RemoveTarget(name string, local bool) error {
+	if local {
+		project, err := LoadProject(ProjectFile)
+		if err != nil {
+			return err
+		}
+		delete(project.Targets, name)
+		return SaveProject(ProjectFile, project)
+	}
+
+	targets, err := loadUserTargets()
+	if err != nil {
+		return err
+	}
+	delete(targets, name)
+	return saveUserTargets(targets)
+}
+
+func loadUserTargets() (map[string]map[string]any, error) {
+	sfDir, err := GetSimpleFlagsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]map[string]any)
+	data, err := ioutil.ReadFile(path.Join(sfDir, targetsFile))
+	if os.IsNotExist(err) {
+		return targets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &targets); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func saveUserTargets(targets map[string]map[string]any) error {
+	sfDir, err := GetSimpleFlagsDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(targets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(sfDir, targetsFile), append(data, '\n'), 0644)
+}
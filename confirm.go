@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/simpleflags/cli/ui"
+	"github.com/simpleflags/services/pkg/model"
+)
+
+var errCancelled = errors.New("operation cancelled")
+
+// confirm asks user for y/N confirmation unless --yes is set
+func confirm(label string) error {
+	if options.Yes {
+		return nil
+	}
+	if !ui.Confirm(ui.PromptContent{Label: label}) {
+		return errCancelled
+	}
+	return nil
+}
+
+// confirmTyped asks user to type expected value unless --yes is set
+func confirmTyped(label, expected string) error {
+	if options.Yes {
+		return nil
+	}
+	if !ui.TypedConfirm(ui.PromptContent{
+		ErrMessage: fmt.Sprintf("Type %s to confirm", expected),
+		Label:      label,
+	}, expected) {
+		return errCancelled
+	}
+	return nil
+}
+
+// confirmProduction asks user to type environment identifier when environment is production
+func confirmProduction(env *model.Environment) error {
+	if !env.Production {
+		return nil
+	}
+	return confirmTyped(fmt.Sprintf("%s is a production environment, type %s to confirm", env.Name, env.Identifier),
+		env.Identifier)
+}
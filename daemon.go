@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// logFile writes every log line as JSON object and can be reopened after rotation
+type logFile struct {
+	mu   sync.Mutex
+	name string
+	file *os.File
+}
+
+type logEntry struct {
+	Time    string `json:"time"`
+	Message string `json:"msg"`
+}
+
+func openLogFile(name string) (*logFile, error) {
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return &logFile{name: name, file: file}, nil
+}
+
+func (l *logFile) Write(p []byte) (int, error) {
+	data, err := json.Marshal(logEntry{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Message: strings.TrimSuffix(string(p), "\n"),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err = l.file.Write(append(data, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// reopen opens file with the same name, used after log file was rotated
+func (l *logFile) reopen() error {
+	file, err := os.OpenFile(l.name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	previous := l.file
+	l.file = file
+	return previous.Close()
+}
+
+func (l *logFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// writePidFile writes id of current process to file, it fails when file belongs to running process
+func writePidFile(file string) error {
+	if data, err := ioutil.ReadFile(file); err == nil {
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err == nil && pid != os.Getpid() && processRunning(pid) {
+			return fmt.Errorf("process %d from pid file %s is still running", pid, file)
+		}
+	}
+	return writeFileAtomic(file, []byte(strconv.Itoa(os.Getpid())+"\n"))
+}
+
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+func readKeyFile(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", errors.New("key file " + file + " is empty")
+	}
+	return key, nil
+}
+
+// watchFile notifies channel whenever modification time or size of file changes
+func watchFile(ctx context.Context, file string, interval time.Duration, changed chan<- struct{}) {
+	var modified time.Time
+	var size int64
+	if info, err := os.Stat(file); err == nil {
+		modified, size = info.ModTime(), info.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(file)
+			if err != nil || (info.ModTime().Equal(modified) && info.Size() == size) {
+				continue
+			}
+			modified, size = info.ModTime(), info.Size()
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
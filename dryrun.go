@@ -0,0 +1,16 @@
+package main
+
+import "fmt"
+
+// dryRun prints request which would be sent to the server when --dry-run is set
+// and reports whether the call has to be skipped
+func dryRun(method, resource string, body any) bool {
+	if !options.DryRun {
+		return false
+	}
+	fmt.Printf("[dry-run] %s %s\n", method, resource)
+	if body != nil {
+		fmt.Print(jsonFormatter("", "  ", body))
+	}
+	return true
+}
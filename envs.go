@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/jedib0t/go-pretty/table"
 	"github.com/simpleflags/services/pkg/model"
 	"log"
@@ -44,13 +45,19 @@ func (c envCommand) create(ctx context.Context) error {
 		return errors.New("-n or --name flag is required")
 	}
 
-	return api.CreateEnvironment(ctx, &model.Environment{
+	body := model.Environment{
 		Account:     c.Account,
 		Identifier:  c.Args.Identifier,
 		Name:        c.Name,
 		Description: c.Description,
 		Production:  c.Production,
-	})
+	}
+
+	if dryRun("POST", "environment", body) {
+		return nil
+	}
+
+	return api.CreateEnvironment(ctx, &body)
 }
 
 func (c envCommand) list(ctx context.Context) error {
@@ -90,6 +97,24 @@ func (c envCommand) remove(ctx context.Context) error {
 		return errors.New("-a or --acc flag is required")
 	}
 
+	env, err := findEnvironment(ctx, c.Account, c.Args.Identifier)
+	if err != nil {
+		return err
+	}
+
+	if dryRun("DELETE", "environment "+env.Identifier, nil) {
+		return nil
+	}
+
+	if env.Production {
+		err = confirmProduction(env)
+	} else {
+		err = confirm(fmt.Sprintf("Remove environment %s", env.Identifier))
+	}
+	if err != nil {
+		return err
+	}
+
 	return api.DeleteEnvironment(ctx, c.Account, c.Args.Identifier)
 }
 
@@ -106,3 +131,16 @@ func init() {
 		log.Printf("error adding command %v", err)
 	}
 }
+
+func findEnvironment(ctx context.Context, account, identifier string) (*model.Environment, error) {
+	envs, err := api.GetEnvironments(ctx, &account)
+	if err != nil {
+		return nil, err
+	}
+	for _, env := range envs {
+		if env.Identifier == identifier {
+			return &env, nil
+		}
+	}
+	return nil, fmt.Errorf("environment %s not found", identifier)
+}
@@ -2,36 +2,171 @@ package main
 
 import (
 	"context"
-	"github.com/antonmedv/expr"
+	"errors"
+	"fmt"
 	"github.com/kr/pretty"
 	"github.com/simpleflags/services/pkg/api/client"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
 type evaluateCommand struct {
-	Account string            `short:"a" long:"acc" description:"Account identifier" env:"SF_ACCOUNT"`
-	Project string            `short:"p" long:"project" description:"Project identifier" env:"SF_PROJECT"`
-	Target  map[string]string `short:"t" long:"target" description:"Target data <property:value>"`
-	Args    struct {
+	Account    string   `short:"a" long:"acc" description:"Account identifier" env:"SF_ACCOUNT"`
+	Project    string   `short:"p" long:"project" description:"Project identifier" env:"SF_PROJECT"`
+	Env        string   `short:"e" long:"env" description:"Environment identifier (used with --explain)"`
+	Target     []string `short:"t" long:"target" description:"Target attribute <key=value> as string or <key:=json> as JSON value"`
+	TargetFile string   `long:"target-file" description:"Target from JSON or YAML file"`
+	TargetJSON string   `long:"target-json" description:"Target as JSON object"`
+	Snapshot   string   `long:"snapshot" description:"Evaluate locally against directory written by pull"`
+	Explain    bool     `long:"explain" description:"Show how each rule was evaluated for the target"`
+	Targets    string   `long:"targets" description:"Evaluate flags for every target in csv or ndjson file, csv header <name>:<type> sets number, bool or json column"`
+	Workers    int      `long:"workers" description:"Number of parallel evaluations with --targets" default:"8"`
+	Output     string   `short:"o" long:"output" description:"Output format with --targets, --as or --all-envs" choice:"table" choice:"csv" choice:"json" default:"table"`
+	As         []string `long:"as" description:"Evaluate as saved target, can be repeated"`
+	AllEnvs    bool     `long:"all-envs" description:"Evaluate in every environment using API keys stored with key --set-env"`
+	Repl       bool     `long:"repl" description:"Start interactive evaluation session"`
+	WithRule   []string `long:"with-rule" description:"Evaluate with additional rule <expression>=<value> without saving it"`
+	WithFile   string   `long:"with-file" description:"Evaluate with flag configuration from JSON or YAML file without saving it"`
+	Args       struct {
 		Identifiers []string `positional-arg-name:"identifiers"`
 	} `positional-args:"yes"`
 }
 
 func (c evaluateCommand) Execute(_ []string) error {
+	if err := c.checkModes(); err != nil {
+		return err
+	}
+
+	target, err := parseTarget(c.Target, c.TargetFile, c.TargetJSON)
+	if err != nil {
+		return err
+	}
+
+	if c.Repl {
+		return c.repl(target)
+	}
+
+	if c.Targets != "" {
+		return c.batch()
+	}
+
+	if len(c.As) > 0 {
+		return c.evaluateAs(target)
+	}
+
+	if c.AllEnvs {
+		return c.allEnvs(target)
+	}
+
+	if len(c.WithRule) > 0 || c.WithFile != "" {
+		return c.whatIf(target)
+	}
+
+	if c.Explain {
+		return c.explain(target)
+	}
+
+	if c.Snapshot != "" {
+		return c.local(target)
+	}
+
 	clientAPI := client.New(os.Getenv("SF_API_KEY"))
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	target := make(map[string]any)
-	for key, val := range c.Target {
-		exprValue, err := expr.Eval(val, nil)
-		target[key] = val
-		if err == nil {
-			target[key] = exprValue
+	evaluate, err := clientAPI.Evaluate(ctx, nil, nil, nil, target, c.Args.Identifiers...)
+	if err != nil {
+		return err
+	}
+	_, err = pretty.Print(jsonFormatter("", "  ", evaluate))
+	return err
+}
+
+// checkModes rejects options which would be silently ignored by selected mode
+func (c evaluateCommand) checkModes() error {
+	target := len(c.Target) > 0 || c.TargetFile != "" || c.TargetJSON != ""
+	whatIf := len(c.WithRule) > 0 || c.WithFile != ""
+	switch {
+	case c.Targets != "":
+		return rejectOptions("--targets", map[string]bool{
+			"--repl":                           c.Repl,
+			"-t, --target-file, --target-json": target,
+			"--explain":                        c.Explain,
+			"--as":                             len(c.As) > 0,
+			"--all-envs":                       c.AllEnvs,
+			"--with-rule, --with-file":         whatIf,
+		})
+	case c.Repl:
+		return rejectOptions("--repl", map[string]bool{
+			"--as":                     len(c.As) > 0,
+			"--all-envs":               c.AllEnvs,
+			"--with-rule, --with-file": whatIf,
+		})
+	case len(c.As) > 0:
+		return rejectOptions("--as", map[string]bool{
+			"--all-envs":               c.AllEnvs,
+			"--with-rule, --with-file": whatIf,
+		})
+	case c.AllEnvs:
+		return rejectOptions("--all-envs", map[string]bool{
+			"--snapshot":               c.Snapshot != "",
+			"--explain":                c.Explain,
+			"--with-rule, --with-file": whatIf,
+		})
+	}
+	return nil
+}
+
+func rejectOptions(mode string, conflicting map[string]bool) error {
+	var used []string
+	for option, set := range conflicting {
+		if set {
+			used = append(used, option)
 		}
 	}
-	evaluate, err := clientAPI.Evaluate(ctx, nil, nil, nil, target, c.Args.Identifiers...)
+	if len(used) == 0 {
+		return nil
+	}
+	sort.Strings(used)
+	return fmt.Errorf("%s can not be combined with %s", mode, strings.Join(used, "; "))
+}
+
+// evaluateAs evaluates flags for saved targets, attributes provided on command line
+// override saved ones
+func (c evaluateCommand) evaluateAs(overrides map[string]any) error {
+	targets, err := savedTargets(c.As)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		for key, val := range overrides {
+			target[key] = val
+		}
+	}
+
+	if c.Explain {
+		for i, target := range targets {
+			fmt.Printf("Target %s\n", c.As[i])
+			if err = c.explain(target); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return c.evaluateAll(c.As, targets)
+}
+
+// local evaluates flags against snapshot without calling the server
+func (c evaluateCommand) local(target map[string]any) error {
+	s, err := loadSnapshot(c.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	evaluate, err := s.evaluate(target, c.Args.Identifiers...)
 	if err != nil {
 		return err
 	}
@@ -39,6 +174,52 @@ func (c evaluateCommand) Execute(_ []string) error {
 	return err
 }
 
+// explain prints evaluation trace of flags, configuration is taken from snapshot
+// when provided, otherwise from the server for selected environment
+func (c evaluateCommand) explain(target map[string]any) error {
+	if len(c.Args.Identifiers) == 0 {
+		return errors.New("provide flag identifiers to explain")
+	}
+
+	s, err := c.configSnapshot()
+	if err != nil {
+		return err
+	}
+
+	for _, identifier := range c.Args.Identifiers {
+		flag, ok := s.Flags[identifier]
+		if !ok {
+			return fmt.Errorf("flag %s not found", identifier)
+		}
+		printTrace(explainFlag(s, flag, target))
+	}
+	return nil
+}
+
+// configSnapshot returns snapshot from disk or builds one for flags from the admin API
+func (c evaluateCommand) configSnapshot() (*snapshot, error) {
+	if c.Snapshot != "" {
+		s, err := loadSnapshot(c.Snapshot)
+		if err != nil {
+			return nil, err
+		}
+		s.Environment = c.Env
+		return s, nil
+	}
+
+	if c.Env == "" {
+		return nil, errors.New("environment -e or --env flag is required")
+	}
+
+	if c.Project == "" {
+		return nil, errors.New("-p or --project flag is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return fetchSnapshot(ctx, c.Account, c.Project, c.Env, c.Args.Identifiers...)
+}
+
 func init() {
 	ec := evaluateCommand{}
 	cmd, err := parser.AddCommand(
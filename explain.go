@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"github.com/antonmedv/expr/ast"
+	exprparser "github.com/antonmedv/expr/parser"
+	"github.com/simpleflags/evaluation"
+	"sort"
+	"strings"
+)
+
+type atomTrace struct {
+	Expression string
+	Matched    bool
+	Err        error
+}
+
+type ruleTrace struct {
+	Expression string
+	Value      any
+	Atoms      []atomTrace
+	Variables  map[string]any
+	Evaluated  bool
+	Matched    bool
+	Err        error
+}
+
+// flagTrace records how flag value was picked for target
+type flagTrace struct {
+	Identifier  string
+	Environment string
+	On          bool
+	Rules       []ruleTrace
+	Matched     int
+	Value       any
+	Err         error
+}
+
+// explainFlag evaluates every rule of flag with the evaluation engine and records result
+// of every step, value is the one engine returns for the whole flag
+func explainFlag(s *snapshot, flag evaluation.Flag, target map[string]any) flagTrace {
+	trace := flagTrace{
+		Identifier:  flag.Identifier,
+		Environment: s.Environment,
+		On:          flag.On,
+		Matched:     -1,
+	}
+	trace.Value, trace.Err = s.evaluateFlag(flag, target)
+
+	for i, rule := range flag.Rules {
+		rt := ruleTrace{
+			Expression: rule.Expression,
+			Value:      rule.Value,
+		}
+		if flag.On && trace.Matched < 0 {
+			rt.Evaluated = true
+			rt.Atoms, rt.Variables = explainAtoms(s, flag.Identifier, rule.Expression, target)
+			rt.Matched, rt.Err = s.evaluateRule(flag.Identifier, rule.Expression, target)
+			if rt.Err == nil && rt.Matched {
+				trace.Matched = i
+			}
+		}
+		trace.Rules = append(trace.Rules, rt)
+	}
+	return trace
+}
+
+// explainAtoms evaluates every operand of && and || in expression separately
+// and returns values of variables referenced by expression
+func explainAtoms(s *snapshot, identifier, expression string, target map[string]any) ([]atomTrace, map[string]any) {
+	tree, err := exprparser.Parse(expression)
+	if err != nil {
+		return nil, nil
+	}
+
+	var atoms []atomTrace
+	rule := disjunctions(tree.Node)
+	// single operand is same as the whole expression so there is nothing to break down
+	if len(rule) > 1 || len(rule[0]) > 1 {
+		for _, conj := range rule {
+			keys := make([]string, 0, len(conj))
+			for key := range conj {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				matched, err := s.evaluateRule(identifier, key, target)
+				atoms = append(atoms, atomTrace{Expression: key, Matched: matched, Err: err})
+			}
+		}
+	}
+
+	v := &variableVisitor{variables: s.Variables, found: make(map[string]any)}
+	ast.Walk(&tree.Node, v)
+	return atoms, v.found
+}
+
+type variableVisitor struct {
+	variables map[string]any
+	found     map[string]any
+}
+
+func (v *variableVisitor) Enter(*ast.Node) {}
+
+func (v *variableVisitor) Exit(node *ast.Node) {
+	if ident, ok := (*node).(*ast.IdentifierNode); ok && ident.Value != "target" {
+		if value, ok := v.variables[ident.Value]; ok {
+			v.found[ident.Value] = value
+		}
+	}
+}
+
+func printTrace(trace flagTrace) {
+	fmt.Printf("Flag %s in environment %s\n", trace.Identifier, trace.Environment)
+	if trace.On {
+		fmt.Println("  State: on")
+	} else {
+		fmt.Println("  State: off, rules are not evaluated")
+	}
+
+	for i, rule := range trace.Rules {
+		fmt.Printf("  Rule %d: %s => %s\n", i+1, rule.Expression, compactJSON(rule.Value))
+		if !rule.Evaluated {
+			fmt.Println("    skipped")
+			continue
+		}
+		for _, atom := range rule.Atoms {
+			if atom.Err != nil {
+				fmt.Printf("    %s: error %v\n", atom.Expression, atom.Err)
+				continue
+			}
+			fmt.Printf("    %s: %t\n", atom.Expression, atom.Matched)
+		}
+		names := make([]string, 0, len(rule.Variables))
+		for name := range rule.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("    variable %s = %s\n", name, compactJSON(rule.Variables[name]))
+		}
+		switch {
+		case rule.Err != nil:
+			fmt.Printf("    result: error %v\n", rule.Err)
+		case rule.Matched:
+			fmt.Println("    result: matched")
+		default:
+			fmt.Println("    result: not matched")
+		}
+	}
+
+	switch {
+	case trace.Err != nil:
+		fmt.Printf("  Value: error %v\n", trace.Err)
+	case trace.Matched >= 0:
+		fmt.Printf("  Value: %s (rule %d)\n", compactJSON(trace.Value), trace.Matched+1)
+	case trace.On:
+		fmt.Printf("  Value: %s (no rule matched)\n", compactJSON(trace.Value))
+	default:
+		fmt.Printf("  Value: %s (off value)\n", compactJSON(trace.Value))
+	}
+}
+
+func compactJSON(value any) string {
+	return strings.TrimSpace(jsonFormatter("", "", value))
+}
@@ -59,7 +59,17 @@ func (c flagCommand) print(ctx context.Context) error {
 		return err
 	}
 	_, err = pretty.Print(jsonFormatter("", "  ", flag))
-	return err
+	if err != nil {
+		return err
+	}
+
+	for _, env := range sortedEnvs(flag.Environments) {
+		for _, problem := range analyzeRules(flag.Environments[env].Rules) {
+			fmt.Printf("Warning: rule %d in environment %s is %s: %s\n",
+				problem.Index+1, env, problem.Kind, problem.Message)
+		}
+	}
+	return nil
 }
 
 func (c flagCommand) list(ctx context.Context) error {
@@ -171,6 +181,10 @@ func (c flagCommand) patch(ctx context.Context) error {
 		}
 	}
 
+	if dryRun("PATCH", "flag "+c.Project+"/"+c.Args.Identifier, instructions) {
+		return nil
+	}
+
 	return api.PatchFlag(ctx, c.Account, c.Project, c.Args.Identifier, &instructions)
 }
 
@@ -228,6 +242,11 @@ func (c flagCommand) create(ctx context.Context) error {
 		Environments: envs,
 		Tags:         c.Tags,
 	}
+
+	if dryRun("POST", "flag", body) {
+		return nil
+	}
+
 	defer func() {
 		if err == nil {
 			fmt.Printf("Flag %s successfully created in project %s", c.Args.Identifier,
@@ -238,7 +257,25 @@ func (c flagCommand) create(ctx context.Context) error {
 }
 
 func (c flagCommand) removeFlag(ctx context.Context) error {
-	err := api.DeleteFlag(ctx, c.Account, c.Project, c.Args.Identifier)
+	flag, err := api.GetFlag(ctx, c.Account, c.Project, c.Args.Identifier)
+	if err != nil {
+		return err
+	}
+
+	if flag.Permanent {
+		return fmt.Errorf("flag %s is permanent and cannot be removed", c.Args.Identifier)
+	}
+
+	if dryRun("DELETE", "flag "+c.Project+"/"+c.Args.Identifier, nil) {
+		return nil
+	}
+
+	if err = confirm(fmt.Sprintf("Remove flag %s (%s) from project %s with configuration in %d environments",
+		flag.Identifier, flag.Name, c.Project, len(flag.Environments))); err != nil {
+		return err
+	}
+
+	err = api.DeleteFlag(ctx, c.Account, c.Project, c.Args.Identifier)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/cenkalti/backoff.v1"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// deadLetter is event which could not be delivered to endpoint
+type deadLetter struct {
+	Endpoint string      `json:"endpoint"`
+	Event    streamEvent `json:"event"`
+	Error    string      `json:"error"`
+	FailedAt time.Time   `json:"failedAt"`
+}
+
+// forwarder posts stream events to webhook endpoints, every endpoint receives events
+// in order and failed deliveries are stored in dead letter directory
+type forwarder struct {
+	endpoints  []string
+	secret     []byte
+	dir        string
+	maxElapsed time.Duration
+	client     *http.Client
+	queues     map[string]chan streamEvent
+	wg         sync.WaitGroup
+}
+
+func newForwarder(endpoints []string, secret, dir string, maxElapsed time.Duration) (*forwarder, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	f := &forwarder{
+		endpoints:  endpoints,
+		secret:     []byte(secret),
+		dir:        dir,
+		maxElapsed: maxElapsed,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		queues:     make(map[string]chan streamEvent),
+	}
+	for _, endpoint := range endpoints {
+		f.queues[endpoint] = make(chan streamEvent, 256)
+	}
+	return f, nil
+}
+
+// start redelivers dead letters of forwarded endpoints and then delivers queued events,
+// once context is cancelled events which were not delivered are moved to dead letters
+func (f *forwarder) start(ctx context.Context) {
+	for endpoint, queue := range f.queues {
+		f.wg.Add(1)
+		go func(endpoint string, queue chan streamEvent) {
+			defer f.wg.Done()
+			f.redeliver(ctx, endpoint)
+			for {
+				select {
+				case <-ctx.Done():
+					f.drain(endpoint, queue)
+					return
+				case event := <-queue:
+					if err := f.deliver(ctx, endpoint, event); err != nil {
+						f.deadLetter(endpoint, event, err)
+					}
+				}
+			}
+		}(endpoint, queue)
+	}
+}
+
+// wait blocks until queues are delivered or drained after context passed to start is cancelled
+func (f *forwarder) wait() {
+	f.wg.Wait()
+}
+
+func (f *forwarder) drain(endpoint string, queue chan streamEvent) {
+	for {
+		select {
+		case event := <-queue:
+			f.deadLetter(endpoint, event, errors.New("forwarding stopped before delivery"))
+		default:
+			return
+		}
+	}
+}
+
+// forward queues event for every endpoint, event goes to dead letters when queue is full
+func (f *forwarder) forward(event streamEvent) {
+	for endpoint, queue := range f.queues {
+		select {
+		case queue <- event:
+		default:
+			f.deadLetter(endpoint, event, fmt.Errorf("delivery queue is full"))
+		}
+	}
+}
+
+func (f *forwarder) deliver(ctx context.Context, endpoint string, event streamEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var strategy backoff.BackOff = &backoff.StopBackOff{}
+	if f.maxElapsed > 0 {
+		exponential := backoff.NewExponentialBackOff()
+		exponential.MaxElapsedTime = f.maxElapsed
+		strategy = exponential
+	}
+	operation := func() error {
+		return f.post(ctx, endpoint, event, body)
+	}
+	notify := func(err error, wait time.Duration) {
+		log.Printf("error forwarding event to %s: %v, retrying in %s", endpoint, err, wait.Round(time.Millisecond))
+	}
+	return backoff.RetryNotify(operation, backoff.WithContext(strategy, ctx), notify)
+}
+
+func (f *forwarder) post(ctx context.Context, endpoint string, event streamEvent, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-SF-Event", event.Type)
+	req.Header.Set("X-SF-Event-ID", event.ID)
+	req.Header.Set("X-SF-Timestamp", timestamp)
+	if len(f.secret) > 0 {
+		req.Header.Set("X-SF-Signature", "sha256="+signPayload(f.secret, timestamp, body))
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("endpoint responded with %s", resp.Status)
+	// client errors will not go away by retrying, except rate limiting
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return backoff.Permanent(err)
+	}
+	return err
+}
+
+// signPayload returns hex encoded HMAC-SHA256 of "<timestamp>.<body>", receivers should
+// compute the same and reject old timestamps to prevent replays
+func signPayload(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *forwarder) deadLetter(endpoint string, event streamEvent, cause error) {
+	letter := deadLetter{
+		Endpoint: endpoint,
+		Event:    event,
+		Error:    cause.Error(),
+		FailedAt: time.Now(),
+	}
+	data, err := json.MarshalIndent(letter, "", "  ")
+	if err != nil {
+		log.Printf("error writing dead letter: %v", err)
+		return
+	}
+	file := path.Join(f.dir, fmt.Sprintf("%d-%s.json", letter.FailedAt.UnixNano(), endpointHash(endpoint)))
+	if err = ioutil.WriteFile(file, data, 0640); err != nil {
+		log.Printf("error writing dead letter: %v", err)
+		return
+	}
+	log.Printf("event %s for %s moved to dead letters: %v", event.Type, endpoint, cause)
+}
+
+// endpointHash identifies endpoint in dead letter file name, URL itself can be longer
+// than file name limit
+func endpointHash(endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return hex.EncodeToString(sum[:16])
+}
+
+// redeliver sends dead letters of endpoint again in order they failed, delivered letters are removed
+func (f *forwarder) redeliver(ctx context.Context, endpoint string) {
+	files, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		log.Printf("error reading dead letters: %v", err)
+		return
+	}
+	suffix := "-" + endpointHash(endpoint) + ".json"
+	for _, info := range files {
+		if !strings.HasSuffix(info.Name(), suffix) {
+			continue
+		}
+		file := path.Join(f.dir, info.Name())
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Printf("error reading dead letter %s: %v", file, err)
+			continue
+		}
+		var letter deadLetter
+		if err = json.Unmarshal(data, &letter); err != nil {
+			log.Printf("invalid dead letter %s: %v", file, err)
+			continue
+		}
+		if err = f.deliver(ctx, endpoint, letter.Event); err != nil {
+			// keep remaining letters in order for next start
+			log.Printf("error redelivering dead letters to %s: %v", endpoint, err)
+			return
+		}
+		if err = os.Remove(file); err != nil {
+			log.Printf("error removing dead letter %s: %v", file, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/MichaelMure/go-term-markdown v0.1.4
 	github.com/antonmedv/expr v1.9.0
+	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e
 	github.com/hashicorp/go-getter v1.6.2
 	github.com/jedib0t/go-pretty v4.3.0+incompatible
 	github.com/jessevdk/go-flags v1.5.0
@@ -15,6 +16,8 @@ require (
 	github.com/simpleflags/evaluation v0.2.1
 	github.com/simpleflags/golang-server-sdk v0.2.1
 	github.com/simpleflags/services v0.0.0-20220813081906-6bcde3577bf5
+	gopkg.in/cenkalti/backoff.v1 v1.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -24,7 +27,6 @@ require (
 	github.com/asaskevich/govalidator v0.0.0-20200907205600-7a23bdc65eef // indirect
 	github.com/aws/aws-sdk-go v1.15.78 // indirect
 	github.com/bgentry/go-netrc v0.0.0-20140422174119-9fd32a8b3d3d // indirect
-	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
 	github.com/danwakefield/fnmatch v0.0.0-20160403171240-cbb64ac3d964 // indirect
 	github.com/disintegration/imaging v1.6.2 // indirect
 	github.com/dlclark/regexp2 v1.1.6 // indirect
@@ -78,5 +80,4 @@ require (
 	google.golang.org/appengine v1.6.1 // indirect
 	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55 // indirect
 	google.golang.org/grpc v1.21.1 // indirect
-	gopkg.in/cenkalti/backoff.v1 v1.1.0 // indirect
 )
@@ -8,7 +8,9 @@ import (
 	"github.com/simpleflags/services/pkg/model"
 	"log"
 	"path"
+	"strings"
 	"time"
+	"unicode"
 )
 
 type apiKeyCommand struct {
@@ -47,14 +49,20 @@ func (c apiKeyCommand) create(ctx context.Context) error {
 		c.flagPermissions(key, val, &permissions)
 	}
 
-	response, err := api.CreateAPIKey(ctx, &model.APIKey{
+	body := model.APIKey{
 		Account:     c.Account,
 		Project:     c.Project,
 		Environment: c.Env,
 		Identifier:  c.Args.Identifier,
 		Name:        c.Name,
 		Permissions: permissions,
-	})
+	}
+
+	if dryRun("POST", "key", body) {
+		return nil
+	}
+
+	response, err := api.CreateAPIKey(ctx, &body)
 	if err != nil {
 		return err
 	}
@@ -194,7 +202,17 @@ func (c apiKeyCommand) flagPermissions(key string, val bool, permissions *model.
 }
 
 func (c apiKeyCommand) remove(ctx context.Context) error {
-	err := api.DeleteAPIKey(ctx, c.Account, c.Project, c.Env, c.Args.Identifier)
+	if dryRun("DELETE", "key "+c.Project+"/"+c.Env+"/"+c.Args.Identifier, nil) {
+		return nil
+	}
+
+	err := confirm(fmt.Sprintf("Remove API key %s from project %s and environment %s",
+		c.Args.Identifier, c.Project, c.Env))
+	if err != nil {
+		return err
+	}
+
+	err = api.DeleteAPIKey(ctx, c.Account, c.Project, c.Env, c.Args.Identifier)
 	if err != nil {
 		return err
 	}
@@ -215,6 +233,9 @@ func (c apiKeyCommand) setAsEnv(apiKey string) error {
 	}
 
 	envs["SF_API_KEY"] = apiKey
+	if c.Env != "" {
+		envs[apiKeyEnvName(c.Project, c.Env)] = apiKey
+	}
 
 	err = godotenv.Write(envs, path.Join(sfDir, ".env"))
 	if err == nil {
@@ -223,6 +244,17 @@ func (c apiKeyCommand) setAsEnv(apiKey string) error {
 	return err
 }
 
+// apiKeyEnvName returns name of env variable holding API key for environment of project
+func apiKeyEnvName(project, env string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, project+"_"+env)
+	return "SF_API_KEY_" + name
+}
+
 func init() {
 	akc := apiKeyCommand{}
 	_, err := parser.AddCommand(
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/simpleflags/cli/config"
+	"github.com/simpleflags/services/pkg/model"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+)
+
+type lintIssue struct {
+	Rule        string
+	Resource    string
+	Environment string
+	Message     string
+}
+
+type linter struct {
+	flags        []model.Flag
+	variables    []model.Variable
+	environments map[string]model.Environment
+}
+
+type lintRule struct {
+	name  string
+	check func(l linter) []lintIssue
+}
+
+var lintRules = []lintRule{
+	{name: "missing-description", check: lintMissingDescription},
+	{name: "deprecated-on-in-prod", check: lintDeprecatedOn},
+	{name: "duplicate-rule", check: lintDuplicateRules},
+	{name: "rule-type-mismatch", check: lintRuleTypes},
+	{name: "unused-variable", check: lintUnusedVariables},
+	{name: "unreachable-rule", check: lintUnreachableRules},
+}
+
+type lintCommand struct {
+	Account string `short:"a" long:"acc" description:"Account identifier" env:"SF_ACCOUNT"`
+	Project string `short:"p" long:"project" description:"Project identifier" required:"true" env:"SF_PROJECT"`
+	Config  string `short:"c" long:"config" description:"Config file with enabled lint rules" default:".simpleflags.json"`
+}
+
+func (c lintCommand) Execute(_ []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadProject(c.Config)
+	if err != nil {
+		return fmt.Errorf("error loading config %s: %w", c.Config, err)
+	}
+
+	flags, err := api.GetFlags(ctx, c.Account, c.Project)
+	if err != nil {
+		return err
+	}
+
+	variables, err := api.GetVariables(ctx, c.Account, &c.Project)
+	if err != nil {
+		return err
+	}
+
+	envs, err := api.GetEnvironments(ctx, &c.Account)
+	if err != nil {
+		return err
+	}
+
+	l := linter{
+		flags:        flags,
+		variables:    variables,
+		environments: make(map[string]model.Environment),
+	}
+	for _, env := range envs {
+		l.environments[env.Identifier] = env
+	}
+
+	var issues []lintIssue
+	for _, rule := range lintRules {
+		if enabled, ok := cfg.Lint.Rules[rule.name]; ok && !enabled {
+			continue
+		}
+		for _, issue := range rule.check(l) {
+			issue.Rule = rule.name
+			issues = append(issues, issue)
+		}
+	}
+
+	if len(issues) == 0 {
+		fmt.Println("No problems found")
+		return nil
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Rule", "Resource", "Environment", "Message"})
+	for _, issue := range issues {
+		t.AppendRow(table.Row{issue.Rule, issue.Resource, issue.Environment, issue.Message})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+
+	return fmt.Errorf("found %d problems", len(issues))
+}
+
+// sortedEnvs returns environment identifiers of flag configurations in stable order
+func sortedEnvs(configurations map[string]model.Configuration) []string {
+	envs := make([]string, 0, len(configurations))
+	for env := range configurations {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+func lintMissingDescription(l linter) []lintIssue {
+	var issues []lintIssue
+	for _, flag := range l.flags {
+		if flag.Description == nil || *flag.Description == "" {
+			issues = append(issues, lintIssue{
+				Resource: "flag " + flag.Identifier,
+				Message:  "missing description",
+			})
+		}
+	}
+	for _, variable := range l.variables {
+		if variable.Description == "" {
+			issues = append(issues, lintIssue{
+				Resource: "var " + variable.Identifier,
+				Message:  "missing description",
+			})
+		}
+	}
+	return issues
+}
+
+func lintDeprecatedOn(l linter) []lintIssue {
+	var issues []lintIssue
+	for _, flag := range l.flags {
+		if !flag.Deprecated {
+			continue
+		}
+		for _, env := range sortedEnvs(flag.Environments) {
+			if l.environments[env].Production && flag.Environments[env].On {
+				issues = append(issues, lintIssue{
+					Resource:    "flag " + flag.Identifier,
+					Environment: env,
+					Message:     "deprecated flag is on in production",
+				})
+			}
+		}
+	}
+	return issues
+}
+
+func lintDuplicateRules(l linter) []lintIssue {
+	var issues []lintIssue
+	for _, flag := range l.flags {
+		for _, env := range sortedEnvs(flag.Environments) {
+			seen := make(map[string]int)
+			for i, rule := range flag.Environments[env].Rules {
+				if first, ok := seen[rule.Expression]; ok {
+					issues = append(issues, lintIssue{
+						Resource:    "flag " + flag.Identifier,
+						Environment: env,
+						Message:     fmt.Sprintf("rule %d duplicates expression of rule %d", i+1, first+1),
+					})
+					continue
+				}
+				seen[rule.Expression] = i
+			}
+		}
+	}
+	return issues
+}
+
+func lintRuleTypes(l linter) []lintIssue {
+	var issues []lintIssue
+	for _, flag := range l.flags {
+		for _, env := range sortedEnvs(flag.Environments) {
+			configuration := flag.Environments[env]
+			offKind := valueKind(configuration.OffValue)
+			for i, rule := range configuration.Rules {
+				kind := valueKind(rule.Value)
+				if offKind != "" && kind != "" && kind != offKind {
+					issues = append(issues, lintIssue{
+						Resource:    "flag " + flag.Identifier,
+						Environment: env,
+						Message:     fmt.Sprintf("rule %d value is %s but off value is %s", i+1, kind, offKind),
+					})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+func lintUnreachableRules(l linter) []lintIssue {
+	var issues []lintIssue
+	for _, flag := range l.flags {
+		for _, env := range sortedEnvs(flag.Environments) {
+			for _, problem := range analyzeRules(flag.Environments[env].Rules) {
+				issues = append(issues, lintIssue{
+					Resource:    "flag " + flag.Identifier,
+					Environment: env,
+					Message:     fmt.Sprintf("rule %d is %s: %s", problem.Index+1, problem.Kind, problem.Message),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+func lintUnusedVariables(l linter) []lintIssue {
+	var issues []lintIssue
+	for _, variable := range l.variables {
+		re := regexp.MustCompile(`\b` + regexp.QuoteMeta(variable.Identifier) + `\b`)
+		used := false
+		for _, flag := range l.flags {
+			for _, configuration := range flag.Environments {
+				for _, rule := range configuration.Rules {
+					used = used || re.MatchString(rule.Expression)
+				}
+			}
+		}
+		if !used {
+			issues = append(issues, lintIssue{
+				Resource: "var " + variable.Identifier,
+				Message:  "variable is not used in any rule",
+			})
+		}
+	}
+	return issues
+}
+
+// valueKind returns JSON kind of value, empty string when value is not set
+func valueKind(value any) string {
+	switch value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func init() {
+	lc := lintCommand{}
+	_, err := parser.AddCommand(
+		"lint",
+		"Check flags and variables in project",
+		"Check flags and variables in project against lint rules, rules can be disabled in config file",
+		&lc,
+	)
+
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+}
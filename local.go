@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"github.com/simpleflags/evaluation"
+	"sort"
+)
+
+// GetFlag, GetFlags and GetVariables implement evaluation.Repository so the engine
+// evaluates flags straight from snapshot, including proposed configurations
+
+func (s *snapshot) GetFlag(identifier string) (evaluation.Flag, error) {
+	flag, ok := s.Flags[identifier]
+	if !ok {
+		return evaluation.Flag{}, fmt.Errorf("flag %s not found in snapshot", identifier)
+	}
+	return flag, nil
+}
+
+func (s *snapshot) GetFlags(identifiers ...string) ([]evaluation.Flag, error) {
+	if len(identifiers) == 0 {
+		for identifier := range s.Flags {
+			identifiers = append(identifiers, identifier)
+		}
+		sort.Strings(identifiers)
+	}
+
+	flags := make([]evaluation.Flag, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		flag, err := s.GetFlag(identifier)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+func (s *snapshot) GetVariables() ([]evaluation.Variable, error) {
+	variables := make([]evaluation.Variable, 0, len(s.Variables))
+	for identifier, value := range s.Variables {
+		variables = append(variables, evaluation.Variable{Identifier: identifier, Value: value})
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Identifier < variables[j].Identifier
+	})
+	return variables, nil
+}
+
+// evaluate returns evaluations of flags for target, when no identifiers are provided
+// all flags from snapshot are evaluated
+func (s *snapshot) evaluate(target map[string]any, identifiers ...string) ([]evaluation.Evaluation, error) {
+	return evaluation.NewEvaluator(s).Evaluate(target, identifiers...)
+}
+
+// with returns copy of snapshot where flag is replaced by given configuration
+func (s *snapshot) with(flag evaluation.Flag) *snapshot {
+	c := newSnapshot(s.Environment)
+	for identifier, f := range s.Flags {
+		c.Flags[identifier] = f
+	}
+	c.Flags[flag.Identifier] = flag
+	c.Variables = s.Variables
+	return c
+}
+
+// evaluateFlag returns value of flag configuration for target
+func (s *snapshot) evaluateFlag(flag evaluation.Flag, target map[string]any) (any, error) {
+	evaluations, err := s.with(flag).evaluate(target, flag.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	if len(evaluations) != 1 {
+		return nil, fmt.Errorf("flag %s was not evaluated", flag.Identifier)
+	}
+	return evaluations[0].Value, nil
+}
+
+// evaluateRule reports whether expression matches target, expression is evaluated by the engine
+// as the only rule of flag which is on so builtins and rollouts behave same as in the flag
+func (s *snapshot) evaluateRule(identifier, expression string, target map[string]any) (bool, error) {
+	value, err := s.evaluateFlag(evaluation.Flag{
+		Identifier: identifier,
+		On:         true,
+		OffValue:   false,
+		Rules:      []evaluation.Rule{{Expression: expression, Value: true}},
+	}, target)
+	if err != nil {
+		return false, err
+	}
+	matched, _ := value.(bool)
+	return matched, nil
+}
+
+// evaluationValues returns values of evaluations by flag identifier
+func evaluationValues(evaluations []evaluation.Evaluation) map[string]any {
+	values := make(map[string]any, len(evaluations))
+	for _, e := range evaluations {
+		values[e.Identifier] = e.Value
+	}
+	return values
+}
@@ -14,7 +14,11 @@ import (
 )
 
 var (
-	parser = flags.NewParser(nil, flags.Default)
+	options struct {
+		Yes    bool `short:"y" long:"yes" description:"Skip confirmation prompts"`
+		DryRun bool `long:"dry-run" description:"Print request body instead of sending it to the server"`
+	}
+	parser = flags.NewParser(&options, flags.Default)
 )
 
 func main() {
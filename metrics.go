@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// runMetrics collects state of long-running commands exposed by --metrics-addr
+type runMetrics struct {
+	mu          sync.Mutex
+	streams     bool
+	ready       bool
+	lastSync    time.Time
+	updates     int
+	connections int
+	reconnects  int
+	errors      int
+}
+
+var metrics = &runMetrics{}
+
+func (m *runMetrics) setReady(ready bool) {
+	m.mu.Lock()
+	m.ready = ready
+	m.mu.Unlock()
+}
+
+func (m *runMetrics) isReady() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ready
+}
+
+// synced records successful sync which changed given number of items
+func (m *runMetrics) synced(updates int) {
+	m.mu.Lock()
+	m.lastSync = time.Now()
+	m.updates += updates
+	m.mu.Unlock()
+}
+
+func (m *runMetrics) connected() {
+	m.mu.Lock()
+	m.connections++
+	m.mu.Unlock()
+}
+
+func (m *runMetrics) disconnected() {
+	m.mu.Lock()
+	m.connections--
+	m.mu.Unlock()
+}
+
+func (m *runMetrics) reconnecting() {
+	m.mu.Lock()
+	m.reconnects++
+	m.mu.Unlock()
+}
+
+func (m *runMetrics) failed() {
+	m.mu.Lock()
+	m.errors++
+	m.mu.Unlock()
+}
+
+// ServeHTTP writes metrics in Prometheus text format
+func (m *runMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var lastSync float64
+	if !m.lastSync.IsZero() {
+		lastSync = float64(m.lastSync.UnixNano()) / float64(time.Second)
+	}
+	ready := 0
+	if m.ready {
+		ready = 1
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetric(w, "sf_ready", "gauge", "Whether initial data was loaded", float64(ready))
+	writeMetric(w, "sf_last_sync_timestamp_seconds", "gauge", "Time of the last successful sync", lastSync)
+	writeMetric(w, "sf_updates_total", "counter", "Number of applied updates", float64(m.updates))
+	if m.streams {
+		writeMetric(w, "sf_stream_connections", "gauge", "Number of open stream connections", float64(m.connections))
+		writeMetric(w, "sf_stream_reconnects_total", "counter", "Number of stream reconnects", float64(m.reconnects))
+	}
+	writeMetric(w, "sf_errors_total", "counter", "Number of errors", float64(m.errors))
+}
+
+func writeMetric(w http.ResponseWriter, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// serveMetrics serves /metrics, /healthz and /readyz on address until context is cancelled,
+// stream metrics are served only by commands which subscribe to stream themselves
+func serveMetrics(ctx context.Context, addr string, streams bool) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error serving metrics: %w", err)
+	}
+
+	metrics.mu.Lock()
+	metrics.streams = streams
+	metrics.mu.Unlock()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !metrics.isReady() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	server := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("error shutting down metrics server: %v", err)
+		}
+	}()
+	go func() {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			log.Printf("error serving metrics: %v", err)
+		}
+	}()
+	return nil
+}
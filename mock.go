@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/r3labs/sse/v2"
+	"github.com/simpleflags/evaluation"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// mockServer serves flags from local file with the client API used by SDKs
+type mockServer struct {
+	file string
+	hub  *sseHub
+
+	mu       sync.RWMutex
+	snapshot *snapshot
+	modified time.Time
+	eventID  int
+}
+
+type mockCommand struct {
+	From   string  `short:"f" long:"from" description:"YAML or JSON file with flags and variables, optional with --replay" default:"flags.yaml"`
+	Listen string  `short:"l" long:"listen" description:"Address to listen on" default:":7001"`
+	Replay string  `long:"replay" description:"Emit events recorded by stream --record once first client connects"`
+	Speed  float64 `long:"speed" description:"Replay speed, 2 replays twice as fast, 0 without delays" default:"1"`
+}
+
+func (c mockCommand) Execute(_ []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	m := &mockServer{file: c.From, hub: newSSEHub()}
+	if _, err := os.Stat(c.From); c.Replay != "" && os.IsNotExist(err) {
+		// replay alone needs no flags, stream events are taken from recording
+		m.snapshot = newSnapshot("")
+	} else {
+		if err = m.reload(); err != nil {
+			return err
+		}
+		go m.watch(ctx)
+	}
+
+	if c.Replay != "" {
+		events, err := readRecording(c.Replay)
+		if err != nil {
+			return err
+		}
+		var once sync.Once
+		m.hub.onSubscribe = func() {
+			once.Do(func() {
+				go m.replay(ctx, c.Replay, events, c.Speed)
+			})
+		}
+	}
+
+	server := &http.Server{Addr: c.Listen, Handler: m}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("error shutting down mock server: %v", err)
+		}
+	}()
+
+	log.Printf("mock server listening on %s with %d flags from %s", c.Listen, len(m.snapshot.Flags), c.From)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// loadMockFile reads flags and variables from file in format
+//
+//	flags:
+//	  dark-mode:
+//	    on: true
+//	    offValue: false
+//	    rules:
+//	      - expression: target.country == "DE"
+//	        value: true
+//	variables:
+//	  countries:
+//	    value: ["DE", "AT"]
+func loadMockFile(file string) (*snapshot, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var content struct {
+		Flags     map[string]evaluation.Flag `json:"flags"`
+		Variables map[string]struct {
+			Value any `json:"value"`
+		} `json:"variables"`
+	}
+	if err = unmarshalConfig(file, data, &content); err != nil {
+		return nil, fmt.Errorf("invalid mock file %s: %w", file, err)
+	}
+
+	s := newSnapshot("")
+	for identifier, flag := range content.Flags {
+		flag.Identifier = identifier
+		s.Flags[identifier] = flag
+	}
+	for identifier, variable := range content.Variables {
+		s.Variables[identifier] = variable.Value
+	}
+	return s, nil
+}
+
+// reload loads file again and publishes stream event for every changed flag and variable
+func (m *mockServer) reload() error {
+	info, err := os.Stat(m.file)
+	if err != nil {
+		return err
+	}
+	s, err := loadMockFile(m.file)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	previous := m.snapshot
+	m.snapshot = s
+	m.modified = info.ModTime()
+	m.mu.Unlock()
+
+	if previous == nil {
+		return nil
+	}
+	for _, msg := range snapshotChanges(previous, s) {
+		m.publish(msg)
+	}
+	return nil
+}
+
+// watch reloads file whenever it is modified, invalid file keeps previous flags
+func (m *mockServer) watch(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(m.file)
+			if err != nil {
+				continue
+			}
+			m.mu.RLock()
+			modified := m.modified
+			m.mu.RUnlock()
+			if info.ModTime().Equal(modified) {
+				continue
+			}
+			if err = m.reload(); err != nil {
+				log.Printf("error reloading %s: %v", m.file, err)
+				m.mu.Lock()
+				m.modified = info.ModTime()
+				m.mu.Unlock()
+				continue
+			}
+			log.Printf("reloaded %s", m.file)
+		}
+	}
+}
+
+func (m *mockServer) replay(ctx context.Context, file string, events []recordedEvent, speed float64) {
+	log.Printf("replaying %d events from %s", len(events), file)
+	if err := replay(ctx, events, speed, m.hub.publish); err != nil {
+		return
+	}
+	log.Printf("replay finished")
+}
+
+func (m *mockServer) publish(msg streamMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("error encoding stream event: %v", err)
+		return
+	}
+	m.mu.Lock()
+	m.eventID++
+	id := m.eventID
+	m.mu.Unlock()
+
+	m.hub.publish(&sse.Event{
+		ID:    []byte(strconv.Itoa(id)),
+		Event: []byte("*"),
+		Data:  data,
+	})
+}
+
+// snapshotChanges returns stream messages describing differences between snapshots
+func snapshotChanges(previous, current *snapshot) []streamMessage {
+	var changes []streamMessage
+	for identifier, flag := range current.Flags {
+		old, ok := previous.Flags[identifier]
+		switch {
+		case !ok:
+			changes = append(changes, streamMessage{Event: "create", Domain: "flag", Identifier: identifier})
+		case !reflect.DeepEqual(old, flag):
+			changes = append(changes, streamMessage{Event: "patch", Domain: "flag", Identifier: identifier})
+		}
+	}
+	for identifier := range previous.Flags {
+		if _, ok := current.Flags[identifier]; !ok {
+			changes = append(changes, streamMessage{Event: "delete", Domain: "flag", Identifier: identifier})
+		}
+	}
+	for identifier, value := range current.Variables {
+		old, ok := previous.Variables[identifier]
+		switch {
+		case !ok:
+			changes = append(changes, streamMessage{Event: "create", Domain: "variable", Identifier: identifier})
+		case !reflect.DeepEqual(old, value):
+			changes = append(changes, streamMessage{Event: "patch", Domain: "variable", Identifier: identifier})
+		}
+	}
+	for identifier := range previous.Variables {
+		if _, ok := current.Variables[identifier]; !ok {
+			changes = append(changes, streamMessage{Event: "delete", Domain: "variable", Identifier: identifier})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Domain != changes[j].Domain {
+			return changes[i].Domain < changes[j].Domain
+		}
+		return changes[i].Identifier < changes[j].Identifier
+	})
+	return changes
+}
+
+func (m *mockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isStreamRequest(r) {
+		m.hub.ServeHTTP(w, r)
+		return
+	}
+
+	m.mu.RLock()
+	s := m.snapshot
+	m.mu.RUnlock()
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case strings.HasSuffix(path, "/evaluate"):
+		m.evaluate(w, r, s)
+	case strings.HasSuffix(path, "/flags"):
+		flags, _ := s.GetFlags()
+		writeJSON(w, http.StatusOK, flags)
+	case strings.Contains(path, "/flags/"):
+		identifier := path[strings.LastIndex(path, "/")+1:]
+		flag, ok := s.Flags[identifier]
+		if !ok {
+			http.Error(w, fmt.Sprintf("flag %s not found", identifier), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, flag)
+	case strings.HasSuffix(path, "/variables"):
+		variables, _ := s.GetVariables()
+		writeJSON(w, http.StatusOK, variables)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// evaluate accepts evaluation.Target in JSON body and returns list of evaluation.Evaluation
+// same as the client API, identifiers are passed in query and all flags are evaluated when none are provided
+func (m *mockServer) evaluate(w http.ResponseWriter, r *http.Request, s *snapshot) {
+	target := make(evaluation.Target)
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+			http.Error(w, fmt.Sprintf("invalid target: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var identifiers []string
+	for _, value := range r.URL.Query()["identifiers"] {
+		identifiers = append(identifiers, strings.Split(value, ",")...)
+	}
+
+	evaluations, err := s.evaluate(target, identifiers...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, evaluations)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("error writing response: %v", err)
+	}
+}
+
+func init() {
+	mc := mockCommand{}
+	_, err := parser.AddCommand(
+		"mock",
+		"Run local mock server",
+		"Serve flags from local file with the evaluate API and stream, reload when file changes",
+		&mc,
+	)
+
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/simpleflags/cli/config"
+	"github.com/simpleflags/services/pkg/model"
+	"io/ioutil"
+	"log"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// panicSnapshot holds on/off state of flags before the kill switch was pulled
+type panicSnapshot struct {
+	Account     string          `json:"account"`
+	Project     string          `json:"project"`
+	Environment string          `json:"environment"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	Flags       map[string]bool `json:"flags"`
+}
+
+type panicCommand struct {
+	Account string   `short:"a" long:"acc" description:"Account identifier" env:"SF_ACCOUNT"`
+	Project string   `short:"p" long:"project" description:"Project identifier" env:"SF_PROJECT"`
+	Env     string   `short:"e" long:"env" description:"Environment identifier"`
+	Tags    []string `short:"t" long:"tag" description:"Turn off only flags with these tags"`
+	Output  string   `short:"o" long:"output" description:"Snapshot file (default is stored in simpleflags directory)"`
+	Restore string   `long:"restore" description:"Restore flag states from snapshot file"`
+}
+
+func (c panicCommand) Execute(_ []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if c.Restore != "" {
+		return c.restore(ctx)
+	}
+
+	if c.Project == "" {
+		return errors.New("-p or --project flag is required")
+	}
+
+	if c.Env == "" {
+		return errors.New("environment -e or --env flag is required")
+	}
+
+	if !options.DryRun {
+		if err := c.confirm(ctx, c.Account, c.Env); err != nil {
+			return err
+		}
+	}
+
+	flags, err := api.GetFlags(ctx, c.Account, c.Project)
+	if err != nil {
+		return err
+	}
+
+	snapshot := panicSnapshot{
+		Account:     c.Account,
+		Project:     c.Project,
+		Environment: c.Env,
+		CreatedAt:   time.Now().UTC(),
+		Flags:       make(map[string]bool),
+	}
+	for _, flag := range flags {
+		if !hasAnyTag(flag.Tags, c.Tags) {
+			continue
+		}
+		snapshot.Flags[flag.Identifier] = flag.Environments[c.Env].On
+	}
+
+	if len(snapshot.Flags) == 0 {
+		fmt.Println("No flags matched")
+		return nil
+	}
+
+	states := make(map[string]bool)
+	for identifier, on := range snapshot.Flags {
+		if on {
+			states[identifier] = false
+		}
+	}
+
+	if options.DryRun {
+		fmt.Printf("[dry-run] snapshot of %d flags is not saved\n", len(snapshot.Flags))
+		return setFlagStates(ctx, snapshot.Account, snapshot.Project, snapshot.Environment, states)
+	}
+
+	file, err := c.saveSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Snapshot of %d flags saved to %s\n", len(snapshot.Flags), file)
+
+	if err = setFlagStates(ctx, snapshot.Account, snapshot.Project, snapshot.Environment, states); err != nil {
+		return err
+	}
+	fmt.Printf("Turned off %d flags in environment %s\n", len(states), c.Env)
+	return nil
+}
+
+func (c panicCommand) restore(ctx context.Context) error {
+	data, err := ioutil.ReadFile(c.Restore)
+	if err != nil {
+		return err
+	}
+
+	var snapshot panicSnapshot
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid snapshot file %s: %w", c.Restore, err)
+	}
+
+	if options.DryRun {
+		return setFlagStates(ctx, snapshot.Account, snapshot.Project, snapshot.Environment, snapshot.Flags)
+	}
+
+	if err = c.confirm(ctx, snapshot.Account, snapshot.Environment); err != nil {
+		return err
+	}
+
+	if err = setFlagStates(ctx, snapshot.Account, snapshot.Project, snapshot.Environment, snapshot.Flags); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d flags in environment %s\n", len(snapshot.Flags), snapshot.Environment)
+	return nil
+}
+
+// confirm asks user to type environment name when environment is production
+func (c panicCommand) confirm(ctx context.Context, account, identifier string) error {
+	env, err := findEnvironment(ctx, account, identifier)
+	if err != nil {
+		return err
+	}
+
+	return confirmProduction(env)
+}
+
+func (c panicCommand) saveSnapshot(snapshot panicSnapshot) (string, error) {
+	file := c.Output
+	if file == "" {
+		sfDir, err := config.GetSimpleFlagsDir()
+		if err != nil {
+			return "", err
+		}
+		file = path.Join(sfDir, fmt.Sprintf("panic-%s-%s-%s.json",
+			snapshot.Project, snapshot.Environment, snapshot.CreatedAt.Format("20060102150405")))
+	}
+	return file, ioutil.WriteFile(file, []byte(jsonFormatter("", "  ", snapshot)), 0644)
+}
+
+// panicWorkers is number of flags updated in parallel
+const panicWorkers = 8
+
+// setFlagStates turns flags on or off in parallel and returns the first error,
+// with --dry-run instructions are only printed
+func setFlagStates(ctx context.Context, account, project, env string, states map[string]bool) error {
+	if options.DryRun {
+		identifiers := make([]string, 0, len(states))
+		for identifier := range states {
+			identifiers = append(identifiers, identifier)
+		}
+		sort.Strings(identifiers)
+		for _, identifier := range identifiers {
+			dryRun("PATCH", "flag "+project+"/"+identifier, flagStateInstructions(env, states[identifier]))
+		}
+		return nil
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+		first  error
+	)
+	jobs := make(chan string)
+	for w := 0; w < panicWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for identifier := range jobs {
+				instructions := flagStateInstructions(env, states[identifier])
+				if err := api.PatchFlag(ctx, account, project, identifier, &instructions); err != nil {
+					mu.Lock()
+					failed = append(failed, identifier)
+					if first == nil {
+						first = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for identifier := range states {
+		jobs <- identifier
+	}
+	close(jobs)
+	wg.Wait()
+
+	if first != nil {
+		sort.Strings(failed)
+		return fmt.Errorf("failed to update flags %v: %w", failed, first)
+	}
+	return nil
+}
+
+func flagStateInstructions(env string, on bool) model.Instructions {
+	var instructions model.Instructions
+	instructions.SetOn.Value = on
+	instructions.SetOn.Environment = env
+	return instructions
+}
+
+func hasAnyTag(tags, filter []string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		for _, f := range filter {
+			if tag == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func init() {
+	pc := panicCommand{}
+	_, err := parser.AddCommand(
+		"panic",
+		"Turn off flags in environment",
+		"Save state of flags in environment to snapshot file and turn them off, use --restore to bring them back",
+		&pc,
+	)
+
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+}
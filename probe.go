@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	sfsdk "github.com/simpleflags/golang-server-sdk"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	probeStream   = "stream"
+	probeSnapshot = "snapshot"
+)
+
+// probeResult holds propagation latencies of single path
+type probeResult struct {
+	Path      string          `json:"path"`
+	Latencies []time.Duration `json:"-"`
+	Timeouts  int             `json:"timeouts"`
+	P50       float64         `json:"p50Ms"`
+	P95       float64         `json:"p95Ms"`
+	P99       float64         `json:"p99Ms"`
+	Max       float64         `json:"maxMs"`
+}
+
+type probeCommand struct {
+	Account    string        `short:"a" long:"acc" description:"Account identifier" env:"SF_ACCOUNT"`
+	Project    string        `short:"p" long:"project" description:"Project identifier" env:"SF_PROJECT"`
+	Env        string        `short:"e" long:"env" description:"Environment identifier" required:"true"`
+	CanaryFlag string        `long:"canary-flag" description:"Dedicated flag toggled by probe" required:"true"`
+	APIKey     string        `short:"k" long:"key" description:"API key of environment, default is key saved by key command or SF_API_KEY"`
+	Count      int           `short:"n" long:"count" description:"Number of toggles" default:"10"`
+	Interval   time.Duration `long:"interval" description:"Pause between toggles" default:"2s"`
+	Timeout    time.Duration `long:"timeout" description:"Maximum time to wait for change to arrive" default:"30s"`
+	Snapshot   string        `long:"snapshot" description:"Measure directory written by running pull instead of starting own SDK"`
+	Output     string        `short:"o" long:"output" description:"Output format" choice:"table" choice:"csv" choice:"json" default:"table"`
+}
+
+func (c probeCommand) Execute(_ []string) (err error) {
+	if c.Project == "" {
+		return errors.New("-p or --project flag is required")
+	}
+	if c.Count < 1 {
+		return errors.New("count must be greater than zero")
+	}
+	apiKey := c.APIKey
+	if apiKey == "" {
+		apiKey = envAPIKey(c.Project, c.Env)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	initial, err := c.state(ctx)
+	if err != nil {
+		return err
+	}
+
+	// nothing can be measured without changing the flag so only toggles are printed
+	if options.DryRun {
+		if err = c.toggle(ctx, !initial); err != nil {
+			return err
+		}
+		return c.toggle(ctx, initial)
+	}
+
+	arrivals := make(chan time.Time, 64)
+	connected := make(chan struct{})
+	var connectedOnce sync.Once
+	go func() {
+		err := subscribeEvents(ctx, streamBaseURL+"/stream", apiKey, "", func() {
+			connectedOnce.Do(func() { close(connected) })
+		}, func(event streamEvent) {
+			if event.Type == eventFlagChanged && event.Identifier == c.CanaryFlag {
+				arrivals <- event.Time
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("stream stopped: %v", err)
+		}
+	}()
+
+	dir := c.Snapshot
+	if dir == "" {
+		if dir, err = ioutil.TempDir("", "sf-probe-"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		if err = startSDK(apiKey, dir); err != nil {
+			return err
+		}
+		defer func() {
+			if err := sfsdk.Close(); err != nil {
+				log.Printf("error while closing client err: %v", err)
+			}
+		}()
+		sfsdk.WaitForInitialization()
+	}
+
+	// changes made before stream is connected would never arrive
+	select {
+	case <-connected:
+	case <-time.After(c.Timeout):
+		return fmt.Errorf("stream did not connect within %s", c.Timeout)
+	case <-ctx.Done():
+		return nil
+	}
+
+	stream := &probeResult{Path: probeStream}
+	snapshot := &probeResult{Path: probeSnapshot}
+	state := initial
+	// flag is restored also when probe fails or is interrupted
+	defer func() {
+		if state == initial {
+			return
+		}
+		restoreCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if restoreErr := c.toggle(restoreCtx, initial); restoreErr != nil {
+			restoreErr = fmt.Errorf("error restoring flag %s: %w", c.CanaryFlag, restoreErr)
+			if err == nil {
+				err = restoreErr
+				return
+			}
+			log.Print(restoreErr)
+		}
+	}()
+
+	for i := 0; i < c.Count && ctx.Err() == nil; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				continue
+			case <-time.After(c.Interval):
+			}
+		}
+		// events of previous toggles arriving late must not be counted
+		for len(arrivals) > 0 {
+			<-arrivals
+		}
+
+		state = !state
+		start := time.Now()
+		if err = c.toggle(ctx, state); err != nil {
+			return err
+		}
+
+		snapshotDone := make(chan time.Duration, 1)
+		go func(expected bool) {
+			snapshotDone <- waitForSnapshot(ctx, dir, c.CanaryFlag, expected, start, c.Timeout)
+		}(state)
+
+		select {
+		case arrived := <-arrivals:
+			stream.Latencies = append(stream.Latencies, arrived.Sub(start))
+		case <-time.After(c.Timeout):
+			stream.Timeouts++
+		case <-ctx.Done():
+		}
+		if latency := <-snapshotDone; latency >= 0 {
+			snapshot.Latencies = append(snapshot.Latencies, latency)
+		} else if ctx.Err() == nil {
+			snapshot.Timeouts++
+		}
+		fmt.Fprintf(os.Stderr, "probe %d/%d done\n", i+1, c.Count)
+	}
+
+	results := []*probeResult{stream, snapshot}
+	for _, result := range results {
+		result.summarize()
+	}
+	if err = printProbe(c.Output, results); err != nil {
+		return err
+	}
+
+	if timeouts := stream.Timeouts + snapshot.Timeouts; timeouts > 0 {
+		return fmt.Errorf("%d changes did not arrive within %s", timeouts, c.Timeout)
+	}
+	return nil
+}
+
+// state returns whether canary flag is on in environment
+func (c probeCommand) state(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	flag, err := api.GetFlag(ctx, c.Account, c.Project, c.CanaryFlag)
+	if err != nil {
+		return false, err
+	}
+	configuration, ok := flag.Environments[c.Env]
+	if !ok {
+		return false, fmt.Errorf("flag %s has no configuration in environment %s", c.CanaryFlag, c.Env)
+	}
+	return configuration.On, nil
+}
+
+func (c probeCommand) toggle(ctx context.Context, on bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	instructions := flagStateInstructions(c.Env, on)
+	if dryRun("PATCH", "flag "+c.Project+"/"+c.CanaryFlag, instructions) {
+		return nil
+	}
+	return api.PatchFlag(ctx, c.Account, c.Project, c.CanaryFlag, &instructions)
+}
+
+// waitForSnapshot polls snapshot directory until flag has expected state and returns time since start,
+// -1 is returned on timeout
+func waitForSnapshot(ctx context.Context, dir, identifier string, on bool, start time.Time, timeout time.Duration) time.Duration {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return -1
+		case <-deadline:
+			return -1
+		case <-ticker.C:
+			s, err := loadSnapshot(dir)
+			if err != nil {
+				continue
+			}
+			if flag, ok := s.Flags[identifier]; ok && flag.On == on {
+				return time.Since(start)
+			}
+		}
+	}
+}
+
+func (r *probeResult) summarize() {
+	sorted := append([]time.Duration{}, r.Latencies...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	r.P50 = milliseconds(percentile(sorted, 50))
+	r.P95 = milliseconds(percentile(sorted, 95))
+	r.P99 = milliseconds(percentile(sorted, 99))
+	if len(sorted) > 0 {
+		r.Max = milliseconds(sorted[len(sorted)-1])
+	}
+}
+
+// percentile returns nearest rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*10) / 10
+}
+
+func printProbe(format string, results []*probeResult) error {
+	if format == "json" {
+		fmt.Print(jsonFormatter("", "  ", results))
+		return nil
+	}
+
+	rows := make([][]string, len(results))
+	for i, r := range results {
+		rows[i] = []string{
+			r.Path,
+			strconv.Itoa(len(r.Latencies)),
+			strconv.Itoa(r.Timeouts),
+			strconv.FormatFloat(r.P50, 'f', 1, 64),
+			strconv.FormatFloat(r.P95, 'f', 1, 64),
+			strconv.FormatFloat(r.P99, 'f', 1, 64),
+			strconv.FormatFloat(r.Max, 'f', 1, 64),
+		}
+	}
+	return printMatrix(format, []string{"Path", "Samples", "Timeouts", "p50 ms", "p95 ms", "p99 ms", "Max ms"}, rows)
+}
+
+func init() {
+	pc := probeCommand{}
+	_, err := parser.AddCommand(
+		"probe",
+		"Measure propagation latency",
+		"Toggle canary flag and measure how long the change takes to arrive over stream and in pulled snapshot",
+		&pc,
+	)
+
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+}
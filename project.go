@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/jedib0t/go-pretty/table"
 	"github.com/simpleflags/services/pkg/model"
 	"log"
@@ -43,12 +44,18 @@ func (c projectCommand) create(ctx context.Context) error {
 		return errors.New("-n or --name flag is required")
 	}
 
-	return api.CreateProject(ctx, &model.Project{
+	body := model.Project{
 		Account:     c.Account,
 		Identifier:  c.Args.Identifier,
 		Name:        c.Name,
 		Description: c.Description,
-	})
+	}
+
+	if dryRun("POST", "project", body) {
+		return nil
+	}
+
+	return api.CreateProject(ctx, &body)
 }
 
 func (c projectCommand) list(ctx context.Context) error {
@@ -86,6 +93,33 @@ func (c projectCommand) remove(ctx context.Context) error {
 		return errors.New("-a or --acc flag is required")
 	}
 
+	flags, err := api.GetFlags(ctx, c.Account, c.Args.Identifier)
+	if err != nil {
+		return err
+	}
+
+	variables, err := api.GetVariables(ctx, c.Account, &c.Args.Identifier)
+	if err != nil {
+		return err
+	}
+
+	for _, flag := range flags {
+		if flag.Permanent {
+			return fmt.Errorf("project %s contains permanent flag %s and cannot be removed",
+				c.Args.Identifier, flag.Identifier)
+		}
+	}
+
+	if dryRun("DELETE", "project "+c.Args.Identifier, nil) {
+		return nil
+	}
+
+	err = confirm(fmt.Sprintf("Remove project %s with %d flags, %d variables and all API keys (count not available)",
+		c.Args.Identifier, len(flags), len(variables)))
+	if err != nil {
+		return err
+	}
+
 	return api.DeleteProject(ctx, c.Account, c.Args.Identifier)
 }
 
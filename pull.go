@@ -1,48 +1,491 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/joho/godotenv"
+	"github.com/simpleflags/cli/config"
 	sfsdk "github.com/simpleflags/golang-server-sdk"
 	"github.com/simpleflags/golang-server-sdk/client"
 	"github.com/simpleflags/golang-server-sdk/connector/simple"
 	"github.com/simpleflags/golang-server-sdk/repository"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 )
 
+// streamBaseURL is address of CDN serving flags to SDKs
+const streamBaseURL = "https://64a55c46.fanoutcdn.com/api"
+
 type pullCommand struct {
-	Interval int      `short:"i" long:"interval" description:"Pull interval (60s)" default:"60"`
-	Flags    []string `short:"f" long:"flags" description:"Flags"`
+	Interval    int      `short:"i" long:"interval" description:"Interval in seconds for writing changes to output directory" default:"1"`
+	Flags       []string `short:"f" long:"flags" description:"Write only these flags"`
+	Dir         string   `short:"d" long:"dir" description:"Output directory" default:"./"`
+	Once        bool     `long:"once" description:"Exit after first complete sync, fails when no complete snapshot is received within --timeout"`
+	Timeout     int      `long:"timeout" description:"Seconds to wait for complete snapshot with --once" default:"60"`
+	MetricsAddr string   `long:"metrics-addr" description:"Serve /metrics, /healthz and /readyz on address, stream connection metrics are not reported because the SDK does not expose them"`
+	Config      string   `short:"c" long:"config" description:"Config file, interval and flags in its pull section override options and are reloaded on SIGHUP" default:".simpleflags.json"`
+	KeyFile     string   `long:"key-file" description:"File with API key, watched for rotation, SF_API_KEY is used when not set and read again from .env on SIGHUP"`
+	PidFile     string   `long:"pid-file" description:"Write process id to file"`
+	LogFile     string   `long:"log-file" description:"Write logs as JSON lines to file, reopened on SIGHUP"`
 }
 
 func (c pullCommand) Execute(_ []string) error {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	fileStorage, err := repository.NewFileStorage("./")
+	var logs *logFile
+	if c.LogFile != "" {
+		var err error
+		if logs, err = openLogFile(c.LogFile); err != nil {
+			return err
+		}
+		defer logs.Close()
+		log.SetFlags(0)
+		log.SetOutput(logs)
+	}
+
+	if c.PidFile != "" {
+		if err := writePidFile(c.PidFile); err != nil {
+			return err
+		}
+		defer os.Remove(c.PidFile)
+	}
+
+	base := c
+	if err := c.loadConfig(base); err != nil {
+		return err
+	}
+	apiKey, err := c.apiKey()
 	if err != nil {
-		log.Fatalf("Error: %v", err)
+		return err
+	}
+
+	if c.MetricsAddr != "" {
+		if err := serveMetrics(ctx, c.MetricsAddr, false); err != nil {
+			return err
+		}
 	}
-	conn := simple.NewHttpConnector(os.Getenv("SF_API_KEY"),
-		simple.WithBaseURL("https://64a55c46.fanoutcdn.com/api"))
-	err = sfsdk.InitWithConnector(conn, client.WithStorage(&fileStorage))
+
+	// SDK writes to staging directory and files are moved to output directory
+	// only when complete so readers never see partially written files
+	staging, err := ioutil.TempDir("", "sf-pull-")
 	if err != nil {
-		log.Printf("could not connect to SF servers %v", err)
+		return err
 	}
+	defer os.RemoveAll(staging)
 
+	// files written to output directory, removed there once they disappear from staging
+	synced := make(map[string]bool)
+	if c.Once {
+		return c.pullOnce(ctx, apiKey, staging, synced)
+	}
+
+	if err = startSDK(apiKey, staging); err != nil {
+		metrics.failed()
+		log.Printf("could not connect to SF servers %v", err)
+	}
 	defer func() {
 		if err := sfsdk.Close(); err != nil {
 			log.Printf("error while closing client err: %v", err)
 		}
 	}()
 	sfsdk.WaitForInitialization()
+	metrics.setReady(true)
+
+	if _, _, err = c.sync(staging, synced); err != nil {
+		return err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	keyChanged := make(chan struct{}, 1)
+	if c.KeyFile != "" {
+		go watchFile(ctx, c.KeyFile, 5*time.Second, keyChanged)
+	}
+
+	ticker := time.NewTicker(c.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			log.Printf("reloading configuration")
+			if logs != nil {
+				if err = logs.reopen(); err != nil {
+					log.Printf("error reopening log file: %v", err)
+				}
+			}
+			if err = c.loadConfig(base); err != nil {
+				log.Printf("error reloading config %s: %v", c.Config, err)
+			}
+			ticker.Reset(c.interval())
+			apiKey = c.reloadKey(apiKey, staging)
+		case <-keyChanged:
+			apiKey = c.reloadKey(apiKey, staging)
+		case <-ticker.C:
+			if _, _, err = c.sync(staging, synced); err != nil {
+				log.Printf("error writing snapshot: %v", err)
+			}
+		}
+	}
+}
 
-	<-ctx.Done()
+// pullOnce writes snapshot once, it fails when client does not initialize or snapshot
+// is not complete before timeout so broken snapshot is never left behind silently
+func (c pullCommand) pullOnce(ctx context.Context, apiKey, staging string, synced map[string]bool) error {
+	if c.Timeout < 1 {
+		return errors.New("timeout must be greater than zero")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.Timeout)*time.Second)
+	defer cancel()
+
+	if err := startSDK(apiKey, staging); err != nil {
+		return fmt.Errorf("could not connect to SF servers: %w", err)
+	}
+	defer func() {
+		if err := sfsdk.Close(); err != nil {
+			log.Printf("error while closing client err: %v", err)
+		}
+	}()
+
+	initialized := make(chan struct{})
+	go func() {
+		sfsdk.WaitForInitialization()
+		close(initialized)
+	}()
+	select {
+	case <-initialized:
+	case <-ctx.Done():
+		return errors.New("client did not initialize before timeout")
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		_, pending, err := c.sync(staging, synced)
+		if err != nil {
+			return err
+		}
+		if pending == 0 && len(synced) > 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if len(synced) == 0 {
+				return errors.New("no flags received before timeout")
+			}
+			return fmt.Errorf("%d files were still being written at timeout", pending)
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadConfig applies pull section of config file on top of command line options
+func (c *pullCommand) loadConfig(base pullCommand) error {
+	cfg, err := config.LoadProject(c.Config)
+	if err != nil {
+		return fmt.Errorf("error loading config %s: %w", c.Config, err)
+	}
+
+	c.Interval, c.Flags = base.Interval, base.Flags
+	if cfg.Pull == nil {
+		return nil
+	}
+	if cfg.Pull.Interval > 0 {
+		c.Interval = cfg.Pull.Interval
+	}
+	if len(cfg.Pull.Flags) > 0 {
+		c.Flags = cfg.Pull.Flags
+	}
 	return nil
 }
 
+func (c pullCommand) interval() time.Duration {
+	if c.Interval <= 0 {
+		return time.Second
+	}
+	return time.Duration(c.Interval) * time.Second
+}
+
+// processAPIKey is SF_API_KEY given to the process, captured before main loads .env file
+// which only sets variables not present in environment
+var processAPIKey = os.Getenv("SF_API_KEY")
+
+// apiKey reads key from key file, without key file SF_API_KEY from environment is used and
+// .env file in data directory is read again when not set, so key stored by key --set-env is picked up on SIGHUP
+func (c pullCommand) apiKey() (string, error) {
+	if c.KeyFile != "" {
+		return readKeyFile(c.KeyFile)
+	}
+	if processAPIKey != "" {
+		return processAPIKey, nil
+	}
+
+	sfDir, err := config.GetSimpleFlagsDir()
+	if err != nil {
+		return "", err
+	}
+	envs, err := godotenv.Read(path.Join(sfDir, ".env"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return envs["SF_API_KEY"], nil
+}
+
+// reloadKey restarts SDK when API key changed, files in output directory are kept
+// until new SDK writes its data so readers never see empty snapshot
+func (c pullCommand) reloadKey(current, staging string) string {
+	apiKey, err := c.apiKey()
+	if err != nil {
+		metrics.failed()
+		log.Printf("error reading API key: %v", err)
+		return current
+	}
+	if apiKey == current {
+		return current
+	}
+
+	log.Printf("API key changed, reconnecting")
+	// not ready until client loads data with the new key
+	metrics.setReady(false)
+	if err = sfsdk.Close(); err != nil {
+		log.Printf("error while closing client err: %v", err)
+	}
+	if err = startSDK(apiKey, staging); err != nil {
+		metrics.failed()
+		log.Printf("error starting client with new API key: %v", err)
+		// next reload retries with the same key
+		return current
+	}
+	go func() {
+		sfsdk.WaitForInitialization()
+		metrics.setReady(true)
+	}()
+	return apiKey
+}
+
+// startSDK initializes SDK which writes flags and variables to directory
+func startSDK(apiKey, dir string) error {
+	fileStorage, err := repository.NewFileStorage(dir)
+	if err != nil {
+		return err
+	}
+	conn := simple.NewHttpConnector(apiKey,
+		simple.WithBaseURL(streamBaseURL))
+	return sfsdk.InitWithConnector(conn, client.WithStorage(&fileStorage))
+}
+
+// sync copies files from staging to output directory and removes files which are no longer
+// in staging, files SDK is writing at the moment are left for the next round and counted as pending
+func (c pullCommand) sync(staging string, synced map[string]bool) (int, int, error) {
+	filter := make(map[string]bool)
+	for _, flag := range c.Flags {
+		filter[flag] = true
+	}
+
+	updated, pending := 0, 0
+	seen := make(map[string]bool)
+	err := filepath.Walk(staging, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(staging, file)
+		if err != nil {
+			return err
+		}
+
+		data, complete, err := readComplete(file, info)
+		if err != nil {
+			return err
+		}
+		if !complete {
+			// keep previous copy until SDK finishes writing
+			seen[rel] = synced[rel]
+			pending++
+			return nil
+		}
+
+		if len(filter) > 0 && strings.HasSuffix(file, ".json") {
+			if data, err = filterSnapshotFile(data, filter); err != nil {
+				return fmt.Errorf("error filtering %s: %w", rel, err)
+			}
+			if data == nil {
+				// file holds only flags which are not selected
+				return nil
+			}
+		}
+		seen[rel] = true
+
+		dest := filepath.Join(c.Dir, rel)
+		if current, err := ioutil.ReadFile(dest); err == nil && bytes.Equal(current, data) {
+			return nil
+		}
+
+		if err = writeFileAtomic(dest, data); err != nil {
+			return err
+		}
+		updated++
+		return nil
+	})
+	if err != nil {
+		metrics.failed()
+		return updated, pending, err
+	}
+
+	for rel := range synced {
+		if seen[rel] {
+			continue
+		}
+		if err = os.Remove(filepath.Join(c.Dir, rel)); err != nil && !os.IsNotExist(err) {
+			metrics.failed()
+			return updated, pending, err
+		}
+		delete(synced, rel)
+		updated++
+	}
+	for rel, ok := range seen {
+		if ok {
+			synced[rel] = true
+		}
+	}
+
+	metrics.synced(updated)
+	return updated, pending, nil
+}
+
+// readComplete reads file and reports whether it was complete, file which changed during
+// reading or json file which can not be parsed is being written
+func readComplete(file string, info os.FileInfo) ([]byte, bool, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	after, err := os.Stat(file)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if after.Size() != info.Size() || !after.ModTime().Equal(info.ModTime()) || int64(len(data)) != after.Size() {
+		return nil, false, nil
+	}
+
+	if strings.HasSuffix(file, ".json") && !json.Valid(data) {
+		return nil, false, nil
+	}
+	return data, true, nil
+}
+
+// filterSnapshotFile removes flags not in filter from file, nil is returned when nothing is left
+func filterSnapshotFile(data []byte, filter map[string]bool) ([]byte, error) {
+	var content any
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+	content = filterSnapshot(content, filter)
+	if content == nil {
+		return nil, nil
+	}
+	return json.Marshal(content)
+}
+
+// filterSnapshot removes flags not listed in identifiers from content of snapshot file,
+// variables are always kept
+func filterSnapshot(content any, identifiers map[string]bool) any {
+	switch value := content.(type) {
+	case []any:
+		result := make([]any, 0, len(value))
+		for _, item := range value {
+			if item = filterSnapshot(item, identifiers); item != nil {
+				result = append(result, item)
+			}
+		}
+		return result
+	case map[string]any:
+		if isSnapshotFlag(value) {
+			identifier, _ := value["identifier"].(string)
+			if !identifiers[identifier] {
+				return nil
+			}
+			return value
+		}
+		if _, ok := value["value"]; ok {
+			return value
+		}
+		result := make(map[string]any)
+		for key, item := range value {
+			if m, ok := item.(map[string]any); ok && isSnapshotFlag(m) && !identifiers[key] {
+				if identifier, _ := m["identifier"].(string); !identifiers[identifier] {
+					continue
+				}
+			}
+			result[key] = item
+		}
+		return result
+	}
+	return content
+}
+
+func isSnapshotFlag(value map[string]any) bool {
+	_, rules := value["rules"]
+	_, offValue := value["offValue"]
+	return rules || offValue
+}
+
+// writeFileAtomic writes data to temporary file in the same directory and renames it
+func writeFileAtomic(file string, data []byte) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
 func init() {
 	sc := pullCommand{}
 	_, err := parser.AddCommand(
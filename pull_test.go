@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsSnapshotFlag(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{`{"identifier": "dark-mode", "on": true, "offValue": false, "rules": []}`, true},
+		{`{"identifier": "dark-mode", "rules": null}`, true},
+		{`{"identifier": "dark-mode", "offValue": "blue"}`, true},
+		{`{"identifier": "countries", "value": ["DE", "AT"]}`, false},
+		{`{"dark-mode": {"on": true}}`, false},
+	}
+	for _, tt := range tests {
+		var value map[string]any
+		if err := json.Unmarshal([]byte(tt.content), &value); err != nil {
+			t.Fatal(err)
+		}
+		if got := isSnapshotFlag(value); got != tt.want {
+			t.Errorf("isSnapshotFlag(%s) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestFilterSnapshot(t *testing.T) {
+	identifiers := map[string]bool{"dark-mode": true}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "selected flag",
+			content: `{"identifier": "dark-mode", "offValue": false, "rules": []}`,
+			want:    `{"identifier": "dark-mode", "offValue": false, "rules": []}`,
+		},
+		{
+			name:    "flag not selected",
+			content: `{"identifier": "beta", "offValue": false, "rules": []}`,
+			want:    `null`,
+		},
+		{
+			name:    "variable",
+			content: `{"identifier": "countries", "value": ["DE"]}`,
+			want:    `{"identifier": "countries", "value": ["DE"]}`,
+		},
+		{
+			name: "list of flags and variables",
+			content: `[{"identifier": "dark-mode", "offValue": false, "rules": []},
+				{"identifier": "beta", "offValue": false, "rules": []},
+				{"identifier": "countries", "value": ["DE"]}]`,
+			want: `[{"identifier": "dark-mode", "offValue": false, "rules": []},
+				{"identifier": "countries", "value": ["DE"]}]`,
+		},
+		{
+			name: "object keyed by identifier",
+			content: `{"dark-mode": {"offValue": false, "rules": []},
+				"beta": {"offValue": false, "rules": []},
+				"countries": {"value": ["DE"]}}`,
+			want: `{"dark-mode": {"offValue": false, "rules": []},
+				"countries": {"value": ["DE"]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content, want any
+			if err := json.Unmarshal([]byte(tt.content), &content); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if got := filterSnapshot(content, identifiers); !reflect.DeepEqual(got, want) {
+				t.Errorf("filterSnapshot() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadComplete(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		modify   func(file string)
+		complete bool
+	}{
+		{name: "json file", file: "flag.json", content: `{"identifier": "dark-mode"}`, complete: true},
+		{name: "torn json file", file: "torn.json", content: `{"identifier": "da`, complete: false},
+		{name: "other file", file: "state", content: `{"identifier": "da`, complete: true},
+		{
+			name:    "written during read",
+			file:    "growing.json",
+			content: `{}`,
+			modify: func(file string) {
+				if err := os.WriteFile(file, []byte(`{"identifier": "dark-mode"}`), 0640); err != nil {
+					t.Fatal(err)
+				}
+			},
+			complete: false,
+		},
+		{
+			name:    "removed",
+			file:    "removed.json",
+			content: `{}`,
+			modify: func(file string) {
+				if err := os.Remove(file); err != nil {
+					t.Fatal(err)
+				}
+			},
+			complete: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(file, []byte(tt.content), 0640); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.modify != nil {
+				tt.modify(file)
+			}
+
+			data, complete, err := readComplete(file, info)
+			if err != nil {
+				t.Fatalf("readComplete: %v", err)
+			}
+			if complete != tt.complete {
+				t.Fatalf("complete = %v, want %v", complete, tt.complete)
+			}
+			if complete && string(data) != tt.content {
+				t.Errorf("data = %q, want %q", data, tt.content)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/r3labs/sse/v2"
+	"os"
+	"time"
+)
+
+// recordedEvent is raw stream event with time it was received, one per line of recording
+type recordedEvent struct {
+	Time  time.Time `json:"time"`
+	ID    string    `json:"id,omitempty"`
+	Event string    `json:"event,omitempty"`
+	Data  string    `json:"data"`
+}
+
+type recorder struct {
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func newRecorder(file string) (*recorder, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	return &recorder{file: f, encoder: json.NewEncoder(f)}, nil
+}
+
+func (r *recorder) record(received time.Time, msg *sse.Event) error {
+	return r.encoder.Encode(recordedEvent{
+		Time:  received,
+		ID:    string(msg.ID),
+		Event: string(msg.Event),
+		Data:  string(msg.Data),
+	})
+}
+
+func (r *recorder) Close() error {
+	return r.file.Close()
+}
+
+func readRecording(file string) ([]recordedEvent, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []recordedEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event recordedEvent
+		if err = json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("invalid event on line %d of %s: %w", line, file, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// replay publishes events keeping gaps between them divided by speed, speed 0 publishes
+// all events at once
+func replay(ctx context.Context, events []recordedEvent, speed float64, publish func(*sse.Event)) error {
+	for i, event := range events {
+		if i > 0 && speed > 0 {
+			gap := time.Duration(float64(event.Time.Sub(events[i-1].Time)) / speed)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(gap):
+			}
+		}
+		publish(&sse.Event{
+			ID:    []byte(event.ID),
+			Event: []byte(event.Event),
+			Data:  []byte(event.Data),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/r3labs/sse/v2"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+	// request is header of request which is repeated when copy is refreshed
+	request http.Header
+}
+
+// relayRefreshWorkers is number of parallel upstream requests when copy of key is refreshed
+const relayRefreshWorkers = 8
+
+// relayKey holds synced copy of responses and stream subscribers of single API key
+type relayKey struct {
+	cancel context.CancelFunc
+	hub    *sseHub
+	// refreshNeeded wakes refresh loop, pending events are published once copy is refreshed
+	refreshNeeded chan struct{}
+
+	mu       sync.Mutex
+	cache    map[string]cachedResponse
+	pending  []*sse.Event
+	lastUsed time.Time
+}
+
+func (k *relayKey) get(uri string) (cachedResponse, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	cached, ok := k.cache[uri]
+	return cached, ok
+}
+
+// set stores response, new responses are not cached once key holds max responses
+func (k *relayKey) set(uri string, cached cachedResponse, max int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.cache[uri]; ok || len(k.cache) < max {
+		k.cache[uri] = cached
+	}
+}
+
+// requestRefresh schedules refresh of copy, event is passed to clients after the refresh
+func (k *relayKey) requestRefresh(event *sse.Event) {
+	if event != nil {
+		k.mu.Lock()
+		k.pending = append(k.pending, event)
+		k.mu.Unlock()
+	}
+	select {
+	case k.refreshNeeded <- struct{}{}:
+	default:
+	}
+}
+
+func (k *relayKey) touch() {
+	k.mu.Lock()
+	k.lastUsed = time.Now()
+	k.mu.Unlock()
+}
+
+// idle reports whether key was not used since given time and has no stream clients
+func (k *relayKey) idle(since time.Time) bool {
+	k.mu.Lock()
+	lastUsed := k.lastUsed
+	k.mu.Unlock()
+	return lastUsed.Before(since) && k.hub.size() == 0
+}
+
+type relay struct {
+	ctx      context.Context
+	upstream string
+	client   *http.Client
+	maxKeys  int
+	maxCache int
+	idle     time.Duration
+
+	mu   sync.Mutex
+	keys map[string]*relayKey
+}
+
+type relayCommand struct {
+	Listen      string `short:"l" long:"listen" description:"Address to listen on" default:":7000"`
+	Upstream    string `short:"u" long:"upstream" description:"Upstream server URL" default:"https://64a55c46.fanoutcdn.com/api"`
+	MaxKeys     int    `long:"max-keys" description:"Maximum number of API keys kept in sync" default:"100"`
+	MaxCache    int    `long:"max-cache" description:"Maximum number of cached responses per API key, other requests are passed to upstream" default:"1000"`
+	Idle        int    `long:"idle" description:"Stop syncing API key not used for given number of seconds" default:"600"`
+	MetricsAddr string `long:"metrics-addr" description:"Serve /metrics, /healthz and /readyz on address"`
+}
+
+func (c relayCommand) Execute(_ []string) error {
+	if c.MaxKeys < 1 {
+		return errors.New("max keys must be greater than zero")
+	}
+	if c.MaxCache < 1 {
+		return errors.New("max cache must be greater than zero")
+	}
+	if c.Idle < 1 {
+		return errors.New("idle must be greater than zero")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	r := &relay{
+		ctx:      ctx,
+		upstream: strings.TrimSuffix(c.Upstream, "/"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+		maxKeys:  c.MaxKeys,
+		maxCache: c.MaxCache,
+		idle:     time.Duration(c.Idle) * time.Second,
+		keys:     make(map[string]*relayKey),
+	}
+	go r.evictIdle()
+
+	server := &http.Server{Addr: c.Listen, Handler: r}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("error shutting down relay: %v", err)
+		}
+	}()
+
+	if c.MetricsAddr != "" {
+		if err := serveMetrics(ctx, c.MetricsAddr, true); err != nil {
+			return err
+		}
+	}
+	metrics.setReady(true)
+
+	log.Printf("relay listening on %s, upstream %s", c.Listen, r.upstream)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// key returns state of API key and starts upstream stream subscription on first use,
+// nil is returned when maximum number of keys is reached
+func (r *relay) key(apiKey string) *relayKey {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k, ok := r.keys[apiKey]; ok {
+		k.touch()
+		return k
+	}
+
+	if len(r.keys) >= r.maxKeys {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	k := &relayKey{
+		cancel:        cancel,
+		hub:           newSSEHub(),
+		refreshNeeded: make(chan struct{}, 1),
+		cache:         make(map[string]cachedResponse),
+		lastUsed:      time.Now(),
+	}
+	r.keys[apiKey] = k
+
+	go r.sync(ctx, apiKey, k)
+	go r.refreshLoop(ctx, k)
+	return k
+}
+
+// sync keeps copy of key in sync with upstream, copy is refreshed after every reconnect
+// because changes could be missed while disconnected, and before every event is passed
+// to clients so they fetch the new version
+func (r *relay) sync(ctx context.Context, apiKey string, k *relayKey) {
+	client := newStreamClient(ctx, r.upstream+"/stream", apiKey)
+	validate := client.ResponseValidator
+	client.ResponseValidator = func(client *sse.Client, resp *http.Response) error {
+		if err := validate(client, resp); err != nil {
+			return err
+		}
+		k.requestRefresh(nil)
+		return nil
+	}
+
+	err := subscribe(ctx, client, k.requestRefresh)
+	if ctx.Err() != nil {
+		return
+	}
+	metrics.failed()
+	log.Printf("upstream stream stopped: %v", err)
+	if errors.Is(err, errStreamRejected) {
+		r.remove(apiKey, k)
+	}
+}
+
+// refreshLoop refreshes copy when requested and then publishes events received meanwhile,
+// events arriving during refresh are coalesced into the next one
+func (r *relay) refreshLoop(ctx context.Context, k *relayKey) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-k.refreshNeeded:
+		}
+
+		k.mu.Lock()
+		events := k.pending
+		k.pending = nil
+		k.mu.Unlock()
+
+		r.refresh(ctx, k)
+		for _, event := range events {
+			k.hub.publish(event)
+		}
+	}
+}
+
+// refresh fetches every cached response again using bounded number of workers,
+// failed requests keep previous response
+func (r *relay) refresh(ctx context.Context, k *relayKey) {
+	k.mu.Lock()
+	uris := make(map[string]http.Header, len(k.cache))
+	for uri, cached := range k.cache {
+		uris[uri] = cached.request
+	}
+	k.mu.Unlock()
+
+	var updated int32
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < relayRefreshWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for uri := range jobs {
+				cached, err := r.fetch(ctx, http.MethodGet, uri, uris[uri], nil)
+				if err != nil || cached.status != http.StatusOK {
+					metrics.failed()
+					continue
+				}
+				if previous, ok := k.get(uri); !ok || !bytes.Equal(previous.body, cached.body) {
+					atomic.AddInt32(&updated, 1)
+				}
+				k.set(uri, cached, r.maxCache)
+			}
+		}()
+	}
+	for uri := range uris {
+		jobs <- uri
+	}
+	close(jobs)
+	wg.Wait()
+
+	metrics.synced(int(updated))
+}
+
+// remove stops syncing API key
+func (r *relay) remove(apiKey string, k *relayKey) {
+	r.mu.Lock()
+	if r.keys[apiKey] == k {
+		delete(r.keys, apiKey)
+	}
+	r.mu.Unlock()
+	k.cancel()
+}
+
+// evictIdle removes keys which were not used for idle duration and have no stream clients
+func (r *relay) evictIdle() {
+	ticker := time.NewTicker(r.idle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			since := time.Now().Add(-r.idle)
+			r.mu.Lock()
+			for apiKey, k := range r.keys {
+				if k.idle(since) {
+					delete(r.keys, apiKey)
+					k.cancel()
+				}
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+func (r *relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	apiKey := req.Header.Get("API-Key")
+	if apiKey == "" {
+		http.Error(w, "missing API-Key header", http.StatusUnauthorized)
+		return
+	}
+	k := r.key(apiKey)
+	if k == nil {
+		http.Error(w, fmt.Sprintf("relay already serves %d API keys", r.maxKeys), http.StatusServiceUnavailable)
+		return
+	}
+
+	if isStreamRequest(req) {
+		k.hub.ServeHTTP(w, req)
+		return
+	}
+
+	if req.Method != http.MethodGet {
+		r.forward(w, req, apiKey, k)
+		return
+	}
+
+	uri := req.URL.RequestURI()
+	if cached, ok := k.get(uri); ok {
+		writeCached(w, cached)
+		return
+	}
+
+	cached, err := r.fetch(req.Context(), req.Method, uri, req.Header, nil)
+	if err != nil {
+		metrics.failed()
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	r.rejected(apiKey, k, cached)
+	if cached.status == http.StatusOK {
+		k.set(uri, cached, r.maxCache)
+	}
+	writeCached(w, cached)
+}
+
+// rejected removes key when upstream responds that it is not valid
+func (r *relay) rejected(apiKey string, k *relayKey, resp cachedResponse) {
+	if resp.status == http.StatusUnauthorized || resp.status == http.StatusForbidden {
+		r.remove(apiKey, k)
+	}
+}
+
+// fetch requests uri from upstream, encoding is left to the transport so responses
+// in the copy are plain and can be served to every client
+func (r *relay) fetch(ctx context.Context, method, uri string, header http.Header, body io.Reader) (cachedResponse, error) {
+	upstreamReq, err := http.NewRequestWithContext(ctx, method, r.upstream+uri, body)
+	if err != nil {
+		return cachedResponse{}, err
+	}
+	header = header.Clone()
+	header.Del("Accept-Encoding")
+	upstreamReq.Header = header
+
+	resp, err := r.client.Do(upstreamReq)
+	if err != nil {
+		return cachedResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return cachedResponse{}, err
+	}
+
+	return cachedResponse{
+		status:  resp.StatusCode,
+		header:  resp.Header.Clone(),
+		body:    data,
+		request: header.Clone(),
+	}, nil
+}
+
+func (r *relay) forward(w http.ResponseWriter, req *http.Request, apiKey string, k *relayKey) {
+	resp, err := r.fetch(req.Context(), req.Method, req.URL.RequestURI(), req.Header, req.Body)
+	if err != nil {
+		metrics.failed()
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	r.rejected(apiKey, k, resp)
+	writeCached(w, resp)
+}
+
+func writeCached(w http.ResponseWriter, resp cachedResponse) {
+	for key, values := range resp.header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.status)
+	if _, err := bytes.NewReader(resp.body).WriteTo(w); err != nil {
+		log.Printf("error writing response: %v", err)
+	}
+}
+
+func init() {
+	rc := relayCommand{}
+	_, err := parser.AddCommand(
+		"relay",
+		"Relay proxy for SDKs",
+		"Serve flags and stream to SDKs from a local copy kept in sync with the server",
+		&rc,
+	)
+
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/simpleflags/cli/config"
+	"github.com/simpleflags/cli/ui"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var replCommands = []string{"set", "unset", "target", "env", "eval", "explain", "flags", "help", "exit"}
+
+const replHelp = `Commands:
+  set <key=value>|<key:=json>...  set target attributes
+  unset <key>...                  remove target attributes
+  target                          show current target
+  env <identifier>                switch environment
+  eval [flags...]                 evaluate flags, typing flag identifiers does the same
+  explain <flags...>              show how rules were evaluated
+  flags                           list flag identifiers
+  exit                            leave
+`
+
+type evalRepl struct {
+	cmd        evaluateCommand
+	target     map[string]any
+	flags      []string
+	attributes []string
+}
+
+// repl runs interactive evaluation session
+func (c evaluateCommand) repl(target map[string]any) error {
+	r := &evalRepl{
+		cmd:    c,
+		target: target,
+	}
+	r.loadCompletions()
+
+	sfDir, err := config.GetSimpleFlagsDir()
+	if err != nil {
+		return err
+	}
+
+	shell, err := ui.NewShell(r.prompt(), path.Join(sfDir, "eval_history"), r.complete)
+	if err != nil {
+		return err
+	}
+	defer shell.Close()
+
+	fmt.Print(replHelp)
+	for {
+		line, err := shell.Readline()
+		if err == ui.ErrInterrupt {
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "exit" || fields[0] == "quit" {
+			return nil
+		}
+		if err = r.run(fields[0], fields[1:]); err != nil {
+			fmt.Printf("error: %v\n", err)
+		}
+		shell.SetPrompt(r.prompt())
+	}
+}
+
+func (r *evalRepl) prompt() string {
+	env := r.cmd.Env
+	if env == "" {
+		env = "default"
+	}
+	return fmt.Sprintf("sf [%s]> ", env)
+}
+
+func (r *evalRepl) run(command string, args []string) error {
+	switch command {
+	case "help":
+		fmt.Print(replHelp)
+	case "set":
+		for _, arg := range args {
+			key, value, err := parseTargetAttribute(arg)
+			if err != nil {
+				return err
+			}
+			r.target[key] = value
+			r.addAttribute(key)
+		}
+	case "unset":
+		for _, arg := range args {
+			delete(r.target, arg)
+		}
+	case "target":
+		fmt.Print(jsonFormatter("", "  ", r.target))
+	case "env":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: env <identifier>")
+		}
+		r.cmd.Env = args[0]
+	case "flags":
+		fmt.Println(strings.Join(r.flags, "\n"))
+	case "explain":
+		cmd := r.cmd
+		cmd.Args.Identifiers = args
+		return cmd.explain(r.target)
+	case "eval":
+		return r.evaluate(args)
+	default:
+		return r.evaluate(append([]string{command}, args...))
+	}
+	return nil
+}
+
+func (r *evalRepl) evaluate(identifiers []string) error {
+	cmd := r.cmd
+	if len(identifiers) > 0 {
+		cmd.Args.Identifiers = identifiers
+	}
+
+	evaluate, err := cmd.evaluator()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	values, err := evaluate(ctx, r.target)
+	if err != nil {
+		return err
+	}
+	fmt.Print(jsonFormatter("", "  ", values))
+	return nil
+}
+
+// loadCompletions collects flag identifiers and attribute names used for tab completion
+func (r *evalRepl) loadCompletions() {
+	if r.cmd.Snapshot != "" {
+		if s, err := loadSnapshot(r.cmd.Snapshot); err == nil {
+			for identifier := range s.Flags {
+				r.flags = append(r.flags, identifier)
+			}
+		}
+	} else if r.cmd.Project != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if flags, err := api.GetFlags(ctx, r.cmd.Account, r.cmd.Project); err == nil {
+			for _, flag := range flags {
+				r.flags = append(r.flags, flag.Identifier)
+			}
+		}
+	}
+	sort.Strings(r.flags)
+
+	for key := range r.target {
+		r.addAttribute(key)
+	}
+	if targets, err := config.LoadTargets(); err == nil {
+		for _, target := range targets {
+			for key := range target {
+				r.addAttribute(key)
+			}
+		}
+	}
+}
+
+func (r *evalRepl) addAttribute(key string) {
+	for _, attribute := range r.attributes {
+		if attribute == key {
+			return
+		}
+	}
+	r.attributes = append(r.attributes, key)
+	sort.Strings(r.attributes)
+}
+
+func (r *evalRepl) complete(line string) []string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || (len(fields) == 1 && !strings.HasSuffix(line, " ")) {
+		return append(append([]string{}, replCommands...), r.flags...)
+	}
+
+	switch fields[0] {
+	case "set":
+		candidates := make([]string, len(r.attributes))
+		for i, attribute := range r.attributes {
+			candidates[i] = attribute + "="
+		}
+		return candidates
+	case "unset":
+		keys := make([]string, 0, len(r.target))
+		for key := range r.target {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	case "env", "target", "flags", "help", "exit":
+		return nil
+	}
+	return r.flags
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/simpleflags/evaluation"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type weightedValue struct {
+	value  any
+	weight float64
+}
+
+// attributeDistribution describes how values of single target attribute are spread
+type attributeDistribution struct {
+	name   string
+	values []weightedValue
+	total  float64
+}
+
+func (d attributeDistribution) pick(r *rand.Rand) any {
+	n := r.Float64() * d.total
+	for _, v := range d.values {
+		if n < v.weight {
+			return v.value
+		}
+		n -= v.weight
+	}
+	return d.values[len(d.values)-1].value
+}
+
+type simulateCommand struct {
+	Account    string   `short:"a" long:"acc" description:"Account identifier" env:"SF_ACCOUNT"`
+	Project    string   `short:"p" long:"project" description:"Project identifier" env:"SF_PROJECT"`
+	Env        string   `short:"e" long:"env" description:"Environment identifier"`
+	Snapshot   string   `long:"snapshot" description:"Use flags from directory written by pull instead of the server"`
+	Population int      `short:"n" long:"population" description:"Number of generated targets" default:"10000"`
+	Attributes []string `long:"attr" description:"Attribute distribution <name>=<value>:<weight>,<value>:<weight>, values are strings, <name>:=... parses them as JSON"`
+	Seed       int64    `long:"seed" description:"Random seed, same seed generates same population" default:"1"`
+	Args       struct {
+		Identifier string `positional-arg-name:"identifier"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c simulateCommand) Execute(_ []string) error {
+	if c.Population < 1 {
+		return errors.New("population must be greater than zero")
+	}
+
+	distributions := make([]attributeDistribution, 0, len(c.Attributes))
+	for _, attr := range c.Attributes {
+		d, err := parseDistribution(attr)
+		if err != nil {
+			return err
+		}
+		distributions = append(distributions, d)
+	}
+
+	s, err := c.snapshot()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := s.Flags[c.Args.Identifier]; !ok {
+		return fmt.Errorf("flag %s not found", c.Args.Identifier)
+	}
+
+	evaluator := evaluation.NewEvaluator(s)
+	r := rand.New(rand.NewSource(c.Seed))
+	counts := make(map[string]int)
+	errs := make(map[string]int)
+	for i := 0; i < c.Population; i++ {
+		target := map[string]any{
+			"identifier": fmt.Sprintf("sim-%d", i),
+		}
+		for _, d := range distributions {
+			target[d.name] = d.pick(r)
+		}
+		evaluations, err := evaluator.Evaluate(target, c.Args.Identifier)
+		if err == nil && len(evaluations) != 1 {
+			err = fmt.Errorf("flag %s was not evaluated", c.Args.Identifier)
+		}
+		if err != nil {
+			errs[err.Error()]++
+			continue
+		}
+		counts[compactJSON(evaluations[0].Value)]++
+	}
+
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] == counts[values[j]] {
+			return values[i] < values[j]
+		}
+		return counts[values[i]] > counts[values[j]]
+	})
+
+	rows := make([][]string, len(values))
+	for i, value := range values {
+		rows[i] = []string{
+			value,
+			strconv.Itoa(counts[value]),
+			fmt.Sprintf("%.2f%%", float64(counts[value])*100/float64(c.Population)),
+		}
+	}
+	if err = printMatrix("table", []string{"Value", "Targets", "Share"}, rows); err != nil {
+		return err
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(errs))
+	failed := 0
+	for message, count := range errs {
+		messages = append(messages, message)
+		failed += count
+	}
+	sort.Strings(messages)
+	for _, message := range messages {
+		fmt.Printf("%d targets: %s\n", errs[message], message)
+	}
+	return fmt.Errorf("evaluation failed for %d of %d targets", failed, c.Population)
+}
+
+func (c simulateCommand) snapshot() (*snapshot, error) {
+	if c.Snapshot != "" {
+		return loadSnapshot(c.Snapshot)
+	}
+
+	if c.Env == "" {
+		return nil, errors.New("environment -e or --env flag is required")
+	}
+
+	if c.Project == "" {
+		return nil, errors.New("-p or --project flag is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return fetchSnapshot(ctx, c.Account, c.Project, c.Env, c.Args.Identifier)
+}
+
+// parseDistribution parses country=DE:40,US:60, values without weight get weight 1,
+// values are strings same as target attributes, plan:=1:50,2:50 parses them as JSON
+func parseDistribution(attr string) (attributeDistribution, error) {
+	d := attributeDistribution{}
+	i := strings.Index(attr, "=")
+	if i <= 0 {
+		return d, fmt.Errorf("invalid attribute %q, expected <name>=<value>:<weight>,...", attr)
+	}
+	d.name = attr[:i]
+	isJSON := strings.HasSuffix(d.name, ":")
+	if isJSON {
+		d.name = strings.TrimSuffix(d.name, ":")
+	}
+
+	for _, part := range strings.Split(attr[i+1:], ",") {
+		value, weight := part, 1.0
+		if j := strings.LastIndex(part, ":"); j >= 0 {
+			w, err := strconv.ParseFloat(part[j+1:], 64)
+			if err != nil || w < 0 {
+				return d, fmt.Errorf("invalid weight in %q", part)
+			}
+			value, weight = part[:j], w
+		}
+		var parsed any = value
+		if isJSON {
+			if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+				return d, fmt.Errorf("invalid json value %q of attribute %s: %w", value, d.name, err)
+			}
+		}
+		d.values = append(d.values, weightedValue{value: parsed, weight: weight})
+		d.total += weight
+	}
+
+	if d.total == 0 {
+		return d, fmt.Errorf("attribute %s has no weight", d.name)
+	}
+	return d, nil
+}
+
+func init() {
+	sc := simulateCommand{}
+	_, err := parser.AddCommand(
+		"simulate",
+		"Simulate flag value distribution",
+		"Evaluate flag locally for generated targets and show share of every value",
+		&sc,
+	)
+
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+}
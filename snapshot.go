@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/simpleflags/evaluation"
+	"github.com/simpleflags/golang-server-sdk/repository"
+	"github.com/simpleflags/services/pkg/model"
+)
+
+// snapshot is local copy of flags and variables of single environment
+type snapshot struct {
+	Environment string
+	Flags       map[string]evaluation.Flag
+	Variables   map[string]any
+}
+
+func newSnapshot(env string) *snapshot {
+	return &snapshot{
+		Environment: env,
+		Flags:       make(map[string]evaluation.Flag),
+		Variables:   make(map[string]any),
+	}
+}
+
+// loadSnapshot reads flags and variables which file storage of the SDK wrote during pull
+func loadSnapshot(dir string) (*snapshot, error) {
+	storage, err := repository.NewFileStorage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := storage.GetFlags()
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot %s: %w", dir, err)
+	}
+	if len(flags) == 0 {
+		return nil, fmt.Errorf("no flags found in snapshot %s", dir)
+	}
+
+	variables, err := storage.GetVariables()
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot %s: %w", dir, err)
+	}
+
+	s := newSnapshot("")
+	for _, flag := range flags {
+		s.Flags[flag.Identifier] = flag
+	}
+	for _, variable := range variables {
+		s.Variables[variable.Identifier] = variable.Value
+	}
+	return s, nil
+}
+
+// fetchSnapshot builds snapshot of flags and project variables in environment from the admin API
+func fetchSnapshot(ctx context.Context, account, project, env string, identifiers ...string) (*snapshot, error) {
+	s := newSnapshot(env)
+	for _, identifier := range identifiers {
+		flag, err := api.GetFlag(ctx, account, project, identifier)
+		if err != nil {
+			return nil, err
+		}
+		s.Flags[identifier], err = flagFrom(identifier, flag.Environments, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	variables, err := api.GetVariables(ctx, account, &project)
+	if err != nil {
+		return nil, err
+	}
+	for _, variable := range variables {
+		s.Variables[variable.Identifier] = variable.Value[env]
+	}
+	return s, nil
+}
+
+// flagFrom converts flag configuration from admin API to the one used by evaluation engine
+func flagFrom(identifier string, configurations map[string]model.Configuration, env string) (evaluation.Flag, error) {
+	configuration, ok := configurations[env]
+	if !ok {
+		return evaluation.Flag{}, fmt.Errorf("flag %s has no configuration in environment %s", identifier, env)
+	}
+	return evaluation.Flag{
+		Identifier: identifier,
+		On:         configuration.On,
+		OffValue:   configuration.OffValue,
+		Rules:      configuration.Rules,
+	}, nil
+}
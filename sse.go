@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/r3labs/sse/v2"
+	"gopkg.in/cenkalti/backoff.v1"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sseHub broadcasts events to every connected SSE client
+type sseHub struct {
+	mu          sync.Mutex
+	subscribers map[chan *sse.Event]struct{}
+	// onSubscribe is called after client is connected
+	onSubscribe func()
+}
+
+func newSSEHub() *sseHub {
+	return &sseHub{
+		subscribers: make(map[chan *sse.Event]struct{}),
+	}
+}
+
+// publish sends event to all subscribers, slow subscribers miss events instead of blocking others
+func (h *sseHub) publish(event *sse.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (h *sseHub) subscribe() chan *sse.Event {
+	ch := make(chan *sse.Event, 1024)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *sseHub) unsubscribe(ch chan *sse.Event) {
+	h.mu.Lock()
+	delete(h.subscribers, ch)
+	h.mu.Unlock()
+}
+
+// size returns number of connected clients
+func (h *sseHub) size() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+func (h *sseHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := h.subscribe()
+	defer h.unsubscribe(ch)
+	if h.onSubscribe != nil {
+		h.onSubscribe()
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-ch:
+			writeSSEEvent(w, event)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSEEvent(w io.Writer, event *sse.Event) {
+	if len(event.ID) > 0 {
+		fmt.Fprintf(w, "id: %s\n", event.ID)
+	}
+	if len(event.Event) > 0 {
+		fmt.Fprintf(w, "event: %s\n", event.Event)
+	}
+	for _, line := range strings.Split(string(event.Data), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// newStreamClient returns SSE client which reconnects with exponential backoff until context is cancelled
+func newStreamClient(ctx context.Context, url, apiKey string) *sse.Client {
+	return sse.NewClient(url, func(c *sse.Client) {
+		c.Headers["API-Key"] = apiKey
+		strategy := backoff.NewExponentialBackOff()
+		strategy.MaxElapsedTime = 0
+		c.ReconnectStrategy = backoff.WithContext(strategy, ctx)
+		c.ResponseValidator = func(c *sse.Client, resp *http.Response) error {
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+					// key is not going to become valid by retrying
+					return backoff.Permanent(fmt.Errorf("%w: %s", errStreamRejected, http.StatusText(resp.StatusCode)))
+				}
+				return fmt.Errorf("could not connect to stream: %s", http.StatusText(resp.StatusCode))
+			}
+			// backoff starts from beginning after successful connection
+			c.ReconnectStrategy.Reset()
+			return nil
+		}
+	})
+}
+
+// subscribe calls handler for every event until context is cancelled, unlike SubscribeWithContext
+// it reconnects also when server closes the stream cleanly, connection state is recorded in metrics
+func subscribe(ctx context.Context, client *sse.Client, handler func(*sse.Event)) error {
+	connected := false
+	validate, notify := client.ResponseValidator, client.ReconnectNotify
+	client.ResponseValidator = func(c *sse.Client, resp *http.Response) error {
+		if validate != nil {
+			if err := validate(c, resp); err != nil {
+				return err
+			}
+		}
+		connected = true
+		metrics.connected()
+		return nil
+	}
+	client.ReconnectNotify = func(err error, wait time.Duration) {
+		if connected {
+			metrics.disconnected()
+			connected = false
+		}
+		metrics.reconnecting()
+		if notify != nil {
+			notify(err, wait)
+		}
+	}
+	defer func() {
+		if connected {
+			metrics.disconnected()
+		}
+	}()
+
+	for {
+		err := client.SubscribeWithContext(ctx, "", handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+
+		wait := client.ReconnectStrategy.NextBackOff()
+		if wait == backoff.Stop {
+			return errStreamClosed
+		}
+		if client.ReconnectNotify != nil {
+			client.ReconnectNotify(errStreamClosed, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+var (
+	errStreamClosed   = errors.New("stream closed by server")
+	errStreamRejected = errors.New("stream rejected API key")
+)
+
+// isStreamRequest reports whether client expects server sent events
+func isStreamRequest(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") ||
+		strings.HasSuffix(r.URL.Path, "/stream")
+}
+
+// streamMessage is payload of stream event sent when flag or variable changes
+type streamMessage struct {
+	Event      string `json:"event"`
+	Domain     string `json:"domain"`
+	Identifier string `json:"identifier"`
+}
@@ -1,31 +1,237 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/r3labs/sse/v2"
+	"github.com/simpleflags/cli/config"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
 )
 
+const (
+	eventFlagChanged     = "flag-changed"
+	eventFlagDeleted     = "flag-deleted"
+	eventVariableChanged = "variable-changed"
+	eventVariableDeleted = "variable-deleted"
+	eventUnknown         = "unknown"
+)
+
+// streamEvent is decoded stream event as printed by stream command
+type streamEvent struct {
+	Time       time.Time       `json:"time"`
+	ID         string          `json:"id,omitempty"`
+	Type       string          `json:"type"`
+	Identifier string          `json:"identifier,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	raw        *sse.Event
+}
+
 type streamCommand struct {
+	APIKey      string   `short:"k" long:"key" description:"API key" env:"SF_API_KEY"`
+	URL         string   `short:"u" long:"url" description:"Stream URL" default:"https://64a55c46.fanoutcdn.com/api/stream"`
+	Flags       []string `long:"flag" description:"Show only events of flag, can be repeated"`
+	Events      []string `long:"event" description:"Show only events of type, can be repeated" choice:"flag-changed" choice:"flag-deleted" choice:"variable-changed" choice:"variable-deleted"`
+	JSON        bool     `long:"json" description:"Print events as JSON lines"`
+	LastEventID string   `long:"last-event-id" description:"Resume stream after event with this id"`
+	Forward     []string `long:"forward" description:"POST every event as JSON to webhook URL, can be repeated"`
+	Secret      string   `long:"secret" description:"Secret used to sign forwarded events with HMAC-SHA256" env:"SF_WEBHOOK_SECRET"`
+	DeadLetters string   `long:"dead-letters" description:"Directory for events which could not be forwarded, default ~/.simpleflags/dead-letters"`
+	Retry       int      `long:"retry" description:"Maximum time in seconds spent retrying single delivery, 0 delivers once without retrying" default:"300"`
+	Record      string   `long:"record" description:"Write every received event with its time to ndjson file, filters are not applied"`
 }
 
 func (c streamCommand) Execute(_ []string) error {
-	client := sse.NewClient("https://64a55c46.fanoutcdn.com/api/stream", func(c *sse.Client) {
-		c.Headers["API-Key"] = os.Getenv("SF_API_KEY")
+	if c.APIKey == "" {
+		return errors.New("API key is required, use -k or SF_API_KEY")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	f, err := c.forwarder()
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		f.start(ctx)
+	}
+
+	var r *recorder
+	if c.Record != "" {
+		if r, err = newRecorder(c.Record); err != nil {
+			return err
+		}
+		defer r.Close()
+	}
+
+	err = subscribeEvents(ctx, c.URL, c.APIKey, c.LastEventID, nil, func(event streamEvent) {
+		if r != nil {
+			if err := r.record(event.Time, event.raw); err != nil {
+				log.Printf("error recording event: %v", err)
+			}
+		}
+		if !c.matches(event) {
+			return
+		}
+		if f != nil {
+			f.forward(event)
+		}
+		if err := c.print(event); err != nil {
+			log.Printf("error printing event: %v", err)
+		}
 	})
-	return client.Subscribe("", func(msg *sse.Event) {
-		// Got some data!
-		fmt.Println(string(msg.Data))
+	if ctx.Err() != nil {
+		err = nil
+	}
+	if f != nil {
+		// events which were not delivered yet are moved to dead letters
+		cancel()
+		f.wait()
+	}
+	return err
+}
+
+func (c streamCommand) forwarder() (*forwarder, error) {
+	if len(c.Forward) == 0 {
+		return nil, nil
+	}
+	dir := c.DeadLetters
+	if dir == "" {
+		sfDir, err := config.GetSimpleFlagsDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = path.Join(sfDir, "dead-letters")
+	}
+	return newForwarder(c.Forward, c.Secret, dir, time.Duration(c.Retry)*time.Second)
+}
+
+// subscribeEvents calls handler for every decoded stream event until context is cancelled,
+// connection state is reported on stderr and connected is called after every successful connection
+func subscribeEvents(ctx context.Context, url, apiKey, lastEventID string, connected func(), handler func(streamEvent)) error {
+	client := newStreamClient(ctx, url, apiKey)
+	client.EventID = lastEventID
+	validate, notify := client.ResponseValidator, client.ReconnectNotify
+	client.ResponseValidator = func(client *sse.Client, resp *http.Response) error {
+		if err := validate(client, resp); err != nil {
+			return err
+		}
+		if client.EventID != "" {
+			fmt.Fprintf(os.Stderr, "connected to %s, resuming after event %s\n", url, client.EventID)
+		} else {
+			fmt.Fprintf(os.Stderr, "connected to %s\n", url)
+		}
+		if connected != nil {
+			connected()
+		}
+		return nil
+	}
+	client.ReconnectNotify = func(err error, wait time.Duration) {
+		if notify != nil {
+			notify(err, wait)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "disconnected: %v, reconnecting in %s\n", err, wait.Round(time.Millisecond))
+	}
+
+	return subscribe(ctx, client, func(msg *sse.Event) {
+		handler(decodeStreamEvent(msg))
 	})
 }
 
+// decodeStreamEvent converts raw SSE event to stream event with type derived from its payload
+func decodeStreamEvent(msg *sse.Event) streamEvent {
+	event := streamEvent{
+		Time: time.Now(),
+		ID:   string(msg.ID),
+		Type: eventUnknown,
+		raw:  msg,
+	}
+	if json.Valid(msg.Data) {
+		event.Data = append(json.RawMessage{}, msg.Data...)
+	} else if len(msg.Data) > 0 {
+		event.Data, _ = json.Marshal(string(msg.Data))
+	}
+
+	var message streamMessage
+	if err := json.Unmarshal(msg.Data, &message); err != nil {
+		return event
+	}
+	event.Identifier = message.Identifier
+
+	deleted := message.Event == "delete"
+	switch {
+	case message.Domain == "flag" && deleted:
+		event.Type = eventFlagDeleted
+	case message.Domain == "flag":
+		event.Type = eventFlagChanged
+	case message.Domain == "variable" && deleted:
+		event.Type = eventVariableDeleted
+	case message.Domain == "variable":
+		event.Type = eventVariableChanged
+	}
+	return event
+}
+
+func (c streamCommand) matches(event streamEvent) bool {
+	if len(c.Events) > 0 && !contains(c.Events, event.Type) {
+		return false
+	}
+	if len(c.Flags) > 0 {
+		isFlag := event.Type == eventFlagChanged || event.Type == eventFlagDeleted
+		return isFlag && contains(c.Flags, event.Identifier)
+	}
+	return true
+}
+
+func (c streamCommand) print(event streamEvent) error {
+	if c.JSON {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	line := []string{event.Time.Format(time.RFC3339), event.Type}
+	if event.Identifier != "" {
+		line = append(line, event.Identifier)
+	}
+	if event.Type == eventUnknown && len(event.Data) > 0 {
+		line = append(line, string(event.Data))
+	}
+	fmt.Println(strings.Join(line, " "))
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
+	sc := streamCommand{}
 	_, err := parser.AddCommand(
 		"stream",
-		"Check stream connection",
-		"Check stream connection",
-		&streamCommand{},
+		"Watch stream events",
+		"Print flag and variable change events from stream, reconnect when connection drops",
+		&sc,
 	)
 
 	if err != nil {
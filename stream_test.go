@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscribeEventsReconnectsAfterServerClose(t *testing.T) {
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&connections, 1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		// every connection is closed cleanly after single event
+		fmt.Fprintf(w, "id: %d\nevent: *\ndata: {\"event\":\"patch\",\"domain\":\"flag\",\"identifier\":\"dark-mode\"}\n\n", n)
+		w.(http.Flusher).Flush()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var ids []string
+	err := subscribeEvents(ctx, server.URL, "key", "", nil, func(event streamEvent) {
+		ids = append(ids, event.ID)
+		if len(ids) == 2 {
+			cancel()
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		t.Fatalf("subscribeEvents: %v", err)
+	}
+	if len(ids) < 2 {
+		t.Fatalf("got %d events, want client to reconnect and receive 2", len(ids))
+	}
+	if ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("got event ids %v, want [1 2]", ids)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/simpleflags/cli/config"
+	"log"
+	"os"
+	"sort"
+)
+
+type targetCommand struct{}
+
+type targetSaveCommand struct {
+	Target     []string `short:"t" long:"target" description:"Target attribute <key=value> as string or <key:=json> as JSON value"`
+	TargetFile string   `long:"target-file" description:"Target from JSON or YAML file"`
+	TargetJSON string   `long:"target-json" description:"Target as JSON object"`
+	Local      bool     `short:"l" long:"local" description:"Save target to project config file instead of simpleflags directory"`
+	Args       struct {
+		Name string `positional-arg-name:"name"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c targetSaveCommand) Execute(_ []string) error {
+	target, err := parseTarget(c.Target, c.TargetFile, c.TargetJSON)
+	if err != nil {
+		return err
+	}
+
+	if len(target) == 0 {
+		return errors.New("provide target attributes with -t, --target-file or --target-json")
+	}
+
+	if err = config.SaveTarget(c.Args.Name, target, c.Local); err != nil {
+		return err
+	}
+	fmt.Printf("Target %s saved\n", c.Args.Name)
+	return nil
+}
+
+type targetListCommand struct{}
+
+func (c targetListCommand) Execute(_ []string) error {
+	targets, err := config.LoadTargets()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Name", "Attributes"})
+	for _, name := range names {
+		t.AppendRow(table.Row{name, compactJSON(targets[name])})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+	return nil
+}
+
+type targetRemoveCommand struct {
+	Local bool `short:"l" long:"local" description:"Remove target from project config file instead of simpleflags directory"`
+	Args  struct {
+		Name string `positional-arg-name:"name"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c targetRemoveCommand) Execute(_ []string) error {
+	if err := config.RemoveTarget(c.Args.Name, c.Local); err != nil {
+		return err
+	}
+	fmt.Printf("Target %s removed\n", c.Args.Name)
+	return nil
+}
+
+// savedTargets returns saved targets by name in same order as names
+func savedTargets(names []string) ([]map[string]any, error) {
+	targets, err := config.LoadTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]any, len(names))
+	for i, name := range names {
+		target, ok := targets[name]
+		if !ok {
+			return nil, fmt.Errorf("target %s not found, use target save to create it", name)
+		}
+		result[i] = target
+	}
+	return result, nil
+}
+
+func init() {
+	tc := targetCommand{}
+	cmd, err := parser.AddCommand(
+		"target",
+		"Saved target commands",
+		"Save, list and remove named targets used with eval --as",
+		&tc,
+	)
+	if err != nil {
+		log.Printf("error adding command %v", err)
+		return
+	}
+
+	_, err = cmd.AddCommand("save", "Save target", "Save target attributes under name", &targetSaveCommand{})
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+
+	_, err = cmd.AddCommand("list", "List targets", "List saved targets", &targetListCommand{})
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+
+	_, err = cmd.AddCommand("rm", "Remove target", "Remove saved target", &targetRemoveCommand{})
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+}
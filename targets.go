@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// parseTarget builds target from file, JSON object and attributes, later sources
+// override attributes from earlier ones
+func parseTarget(attributes []string, file, jsonObject string) (map[string]any, error) {
+	target := make(map[string]any)
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &target)
+		default:
+			err = json.Unmarshal(data, &target)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid target file %s: %w", file, err)
+		}
+	}
+
+	if jsonObject != "" {
+		if err := json.Unmarshal([]byte(jsonObject), &target); err != nil {
+			return nil, fmt.Errorf("invalid target json: %w", err)
+		}
+	}
+
+	for _, attribute := range attributes {
+		key, value, err := parseTargetAttribute(attribute)
+		if err != nil {
+			return nil, err
+		}
+		target[key] = value
+	}
+	return target, nil
+}
+
+// parseTargetAttribute parses key=value or key:value as string and key:=value as JSON
+func parseTargetAttribute(attribute string) (string, any, error) {
+	i := strings.IndexAny(attribute, "=:")
+	if i <= 0 {
+		return "", nil, fmt.Errorf("invalid target attribute %q, expected key=value", attribute)
+	}
+
+	key := attribute[:i]
+	if strings.HasPrefix(attribute[i:], ":=") {
+		var value any
+		if err := json.Unmarshal([]byte(attribute[i+2:]), &value); err != nil {
+			return "", nil, fmt.Errorf("invalid json value of target attribute %s: %w", key, err)
+		}
+		return key, value, nil
+	}
+	return key, attribute[i+1:], nil
+}
+
+// csvColumn is attribute name and type of csv column, header <name>:<type> sets
+// type of the column, cells are strings when type is not provided
+type csvColumn struct {
+	name string
+	kind string
+}
+
+func parseCSVHeader(header []string) ([]csvColumn, error) {
+	columns := make([]csvColumn, len(header))
+	for i, name := range header {
+		columns[i] = csvColumn{name: name, kind: "string"}
+		if j := strings.LastIndex(name, ":"); j > 0 {
+			columns[i] = csvColumn{name: name[:j], kind: name[j+1:]}
+		}
+		switch columns[i].kind {
+		case "string", "number", "bool", "json":
+		default:
+			return nil, fmt.Errorf("unsupported type %s of csv column %s, use string, number, bool or json", columns[i].kind, columns[i].name)
+		}
+	}
+	return columns, nil
+}
+
+func (c csvColumn) value(val string) (any, error) {
+	switch c.kind {
+	case "number":
+		return strconv.ParseFloat(val, 64)
+	case "bool":
+		return strconv.ParseBool(val)
+	case "json":
+		var value any
+		err := json.Unmarshal([]byte(val), &value)
+		return value, err
+	}
+	return val, nil
+}
+
+// readTargets loads targets from csv file with header row or from ndjson file
+func readTargets(file string) ([]map[string]any, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return readCSVTargets(f)
+	case ".ndjson", ".jsonl", ".json":
+		return readNDJSONTargets(f)
+	}
+	return nil, fmt.Errorf("unsupported targets file %s, use .csv or .ndjson", file)
+}
+
+func readCSVTargets(r io.Reader) ([]map[string]any, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns, err := parseCSVHeader(rows[0])
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]map[string]any, 0, len(rows)-1)
+	for line, row := range rows[1:] {
+		target := make(map[string]any)
+		for i, val := range row {
+			if i >= len(columns) || val == "" {
+				continue
+			}
+			value, err := columns[i].value(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value of column %s on line %d: %w", columns[i].kind, columns[i].name, line+2, err)
+			}
+			target[columns[i].name] = value
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func readNDJSONTargets(r io.Reader) ([]map[string]any, error) {
+	var targets []map[string]any
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		target := make(map[string]any)
+		if err := json.Unmarshal([]byte(text), &target); err != nil {
+			return nil, fmt.Errorf("invalid target on line %d: %w", line, err)
+		}
+		targets = append(targets, target)
+	}
+	return targets, scanner.Err()
+}
@@ -101,3 +101,43 @@ func Password(pc PromptContent) string {
 
 	return result
 }
+
+func TypedConfirm(pc PromptContent, expected string) bool {
+	validate := func(input string) error {
+		if input != expected {
+			return errors.New(pc.ErrMessage)
+		}
+		return nil
+	}
+
+	templates := &promptui.PromptTemplates{
+		Prompt:  "{{ . }} ",
+		Valid:   "{{ . | green }} ",
+		Invalid: "{{ . | red }} ",
+		Success: "{{ . | bold }} ",
+	}
+
+	prompt := promptui.Prompt{
+		Label:     pc.Label + ":",
+		Templates: templates,
+		Validate:  validate,
+	}
+
+	result, err := prompt.Run()
+	if err != nil {
+		fmt.Printf("Prompt failed %v\n", err)
+		return false
+	}
+
+	return result == expected
+}
+
+func Confirm(pc PromptContent) bool {
+	prompt := promptui.Prompt{
+		Label:     pc.Label,
+		IsConfirm: true,
+	}
+
+	_, err := prompt.Run()
+	return err == nil
+}
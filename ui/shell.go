@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"github.com/chzyer/readline"
+	"strings"
+)
+
+// ErrInterrupt is returned by Readline when user presses ctrl+c
+var ErrInterrupt = readline.ErrInterrupt
+
+// Completer returns candidates for the word under cursor, line contains text before cursor
+type Completer func(line string) []string
+
+type completer struct {
+	complete Completer
+}
+
+func (c completer) Do(line []rune, pos int) ([][]rune, int) {
+	text := string(line[:pos])
+	word := text[strings.LastIndexAny(text, " ")+1:]
+
+	var candidates [][]rune
+	for _, candidate := range c.complete(text) {
+		if !strings.HasPrefix(candidate, word) {
+			continue
+		}
+		suffix := candidate[len(word):]
+		if !strings.HasSuffix(candidate, "=") {
+			suffix += " "
+		}
+		candidates = append(candidates, []rune(suffix))
+	}
+	return candidates, len([]rune(word))
+}
+
+// Shell reads lines with history and tab completion
+type Shell struct {
+	instance *readline.Instance
+}
+
+func NewShell(prompt, historyFile string, complete Completer) (*Shell, error) {
+	instance, err := readline.NewEx(&readline.Config{
+		Prompt:          prompt,
+		HistoryFile:     historyFile,
+		AutoComplete:    completer{complete: complete},
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Shell{instance: instance}, nil
+}
+
+func (s *Shell) SetPrompt(prompt string) {
+	s.instance.SetPrompt(prompt)
+}
+
+// Readline returns next line, io.EOF is returned on ctrl+d and readline.ErrInterrupt on ctrl+c
+func (s *Shell) Readline() (string, error) {
+	return s.instance.Readline()
+}
+
+func (s *Shell) Close() error {
+	return s.instance.Close()
+}
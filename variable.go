@@ -108,6 +108,10 @@ func (c *variableCommand) create(ctx context.Context) error {
 		Value:       values,
 	}
 
+	if dryRun("POST", "variable", body) {
+		return nil
+	}
+
 	if err := api.CreateVariable(ctx, &body); err != nil {
 		return err
 	}
@@ -131,6 +135,9 @@ func (c *variableCommand) patch(ctx context.Context) error {
 		body := model.PatchVariable{
 			Value: newValue,
 		}
+		if dryRun("PATCH", "variable "+env+"/"+c.Args.Identifier, body) {
+			continue
+		}
 		if err = api.PatchVariable(ctx, c.Account, c.Project, env, c.Args.Identifier, &body); err != nil {
 			return err
 		}
@@ -151,11 +158,24 @@ func (c *variableCommand) print(ctx context.Context) error {
 }
 
 func (c *variableCommand) remove(ctx context.Context) error {
+	project := "global"
+	if c.Project != nil {
+		project = *c.Project
+	}
+
+	if dryRun("DELETE", "variable "+project+"/"+c.Args.Identifier, nil) {
+		return nil
+	}
+
+	if err := confirm(fmt.Sprintf("Remove variable %s from project %s", c.Args.Identifier, project)); err != nil {
+		return err
+	}
+
 	err := api.DeleteVariable(ctx, c.Account, c.Project, c.Args.Identifier)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Variable %s successfully removed from project %s", c.Args.Identifier, c.Project)
+	fmt.Printf("Variable %s successfully removed from project %s", c.Args.Identifier, project)
 	return nil
 }
 
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+type verifyCommand struct {
+	APIKey     string   `short:"k" long:"key" description:"API key used for remote evaluation" env:"SF_API_KEY"`
+	Snapshot   string   `long:"snapshot" description:"Directory written by pull" required:"true"`
+	Target     []string `short:"t" long:"target" description:"Target attribute <key=value> as string or <key:=json> as JSON value"`
+	TargetFile string   `long:"target-file" description:"Target from JSON or YAML file"`
+	TargetJSON string   `long:"target-json" description:"Target as JSON object"`
+	Targets    string   `long:"targets" description:"Verify every target in csv or ndjson file, csv header <name>:<type> sets number, bool or json column"`
+	As         []string `long:"as" description:"Verify saved target, can be repeated"`
+	Args       struct {
+		Identifiers []string `positional-arg-name:"identifiers"`
+	} `positional-args:"yes"`
+}
+
+func (c verifyCommand) Execute(_ []string) error {
+	s, err := loadSnapshot(c.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	names, targets, err := c.targets()
+	if err != nil {
+		return err
+	}
+
+	// without identifiers server evaluates all its flags so flags missing in snapshot are found too
+	remote := remoteEvaluator(c.APIKey, c.Args.Identifiers...)
+	compared := make(map[string]bool)
+	var rows [][]string
+	for i, target := range targets {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		values, err := remote(ctx, target)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		label := targetLabel(i, batchResult{Name: names[i], Target: target})
+		for _, identifier := range c.identifiers(s, values) {
+			compared[identifier] = true
+			remoteValue := missingValue
+			if v, ok := values[identifier]; ok {
+				remoteValue = compactJSON(v)
+			}
+			snapshotValue := localValue(s, identifier, target)
+			if snapshotValue != remoteValue {
+				rows = append(rows, []string{label, identifier, remoteValue, snapshotValue})
+			}
+		}
+	}
+
+	if len(rows) == 0 {
+		fmt.Printf("Snapshot matches server for %d flags and %d targets\n", len(compared), len(targets))
+		return nil
+	}
+
+	if err = printMatrix("table", []string{"Target", "Flag", "Server", "Snapshot"}, rows); err != nil {
+		return err
+	}
+	return fmt.Errorf("found %d mismatches", len(rows))
+}
+
+const missingValue = "<missing>"
+
+// identifiers returns flags to compare, positional identifiers or all flags known
+// to server or snapshot
+func (c verifyCommand) identifiers(s *snapshot, remote map[string]any) []string {
+	if len(c.Args.Identifiers) > 0 {
+		return c.Args.Identifiers
+	}
+	seen := make(map[string]bool)
+	for identifier := range remote {
+		seen[identifier] = true
+	}
+	for identifier := range s.Flags {
+		seen[identifier] = true
+	}
+	return sortedKeys(seen)
+}
+
+// localValue evaluates single flag against snapshot, flag not in snapshot and evaluation
+// errors are reported as value so they show up as mismatch
+func localValue(s *snapshot, identifier string, target map[string]any) string {
+	if _, ok := s.Flags[identifier]; !ok {
+		return missingValue
+	}
+	evaluations, err := s.evaluate(target, identifier)
+	if err != nil {
+		return fmt.Sprintf("<error: %v>", err)
+	}
+	value, ok := evaluationValues(evaluations)[identifier]
+	if !ok {
+		return missingValue
+	}
+	return compactJSON(value)
+}
+
+// targets returns targets to verify with their names, command line target is used
+// when no file or saved targets are provided
+func (c verifyCommand) targets() ([]string, []map[string]any, error) {
+	if c.Targets != "" {
+		targets, err := readTargets(c.Targets)
+		return make([]string, len(targets)), targets, err
+	}
+
+	overrides, err := parseTarget(c.Target, c.TargetFile, c.TargetJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(c.As) == 0 {
+		return []string{""}, []map[string]any{overrides}, nil
+	}
+
+	targets, err := savedTargets(c.As)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, target := range targets {
+		for key, val := range overrides {
+			target[key] = val
+		}
+	}
+	return c.As, targets, nil
+}
+
+func init() {
+	vc := verifyCommand{}
+	_, err := parser.AddCommand(
+		"verify",
+		"Compare snapshot with server",
+		"Evaluate flags remotely and against pulled snapshot and report every mismatch",
+		&vc,
+	)
+
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+}
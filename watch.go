@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+type watchCommand struct {
+	APIKey      string        `short:"k" long:"key" description:"API key" env:"SF_API_KEY"`
+	URL         string        `short:"u" long:"url" description:"Stream URL" default:"https://64a55c46.fanoutcdn.com/api/stream"`
+	Flags       []string      `long:"flag" description:"React only to changes of flag, can be repeated"`
+	Exec        string        `long:"exec" description:"Shell command to run when flags change"`
+	Signal      string        `long:"signal" description:"Signal sent to process from pid file when flags change" choice:"HUP" choice:"INT" choice:"QUIT" choice:"TERM" choice:"USR1" choice:"USR2"`
+	PidFile     string        `long:"pid-file" description:"File with id of process receiving signal"`
+	Debounce    time.Duration `long:"debounce" description:"Wait for further changes before running hook" default:"1s"`
+	MaxWait     time.Duration `long:"max-wait" description:"Run hook at latest this long after first change even when changes keep arriving" default:"30s"`
+	MetricsAddr string        `long:"metrics-addr" description:"Serve /metrics, /healthz and /readyz on address"`
+}
+
+// changes collects identifiers changed since hook was run last time
+type changes struct {
+	flags     map[string]bool
+	variables map[string]bool
+}
+
+func (c *changes) empty() bool {
+	return len(c.flags) == 0 && len(c.variables) == 0
+}
+
+func (c watchCommand) Execute(_ []string) error {
+	if c.APIKey == "" {
+		return errors.New("API key is required, use -k or SF_API_KEY")
+	}
+	if c.Exec == "" && c.Signal == "" {
+		return errors.New("--exec or --signal is required")
+	}
+	if c.Signal != "" && c.PidFile == "" {
+		return errors.New("--pid-file is required with --signal")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	events := make(chan streamEvent, 64)
+	if c.MetricsAddr != "" {
+		if err := serveMetrics(ctx, c.MetricsAddr, true); err != nil {
+			return err
+		}
+	}
+	go c.debounce(ctx, events)
+
+	connected := func() {
+		metrics.setReady(true)
+	}
+	err := subscribeEvents(ctx, c.URL, c.APIKey, "", connected, func(event streamEvent) {
+		if !c.relevant(event) {
+			return
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+func (c watchCommand) relevant(event streamEvent) bool {
+	switch event.Type {
+	case eventFlagChanged, eventFlagDeleted:
+		return len(c.Flags) == 0 || contains(c.Flags, event.Identifier)
+	case eventVariableChanged, eventVariableDeleted:
+		// variables can change value of any flag
+		return true
+	}
+	return false
+}
+
+// debounce runs hook once no change arrived for debounce period or max wait passed since
+// first pending change, hook is never run concurrently and changes arriving while it runs
+// are collected for the next run
+func (c watchCommand) debounce(ctx context.Context, events <-chan streamEvent) {
+	pending := &changes{flags: make(map[string]bool), variables: make(map[string]bool)}
+	timer := time.NewTimer(c.Debounce)
+	timer.Stop()
+	var first time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			if pending.empty() {
+				first = time.Now()
+			}
+			switch event.Type {
+			case eventFlagChanged, eventFlagDeleted:
+				pending.flags[event.Identifier] = true
+			default:
+				pending.variables[event.Identifier] = true
+			}
+			delay := c.Debounce
+			if c.MaxWait > 0 {
+				if remaining := c.MaxWait - time.Since(first); remaining < delay {
+					delay = remaining
+				}
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(delay)
+		case <-timer.C:
+			if pending.empty() {
+				continue
+			}
+			if err := c.run(ctx, pending); err != nil {
+				metrics.failed()
+				log.Printf("error running hook: %v", err)
+			} else {
+				metrics.synced(len(pending.flags) + len(pending.variables))
+			}
+			pending = &changes{flags: make(map[string]bool), variables: make(map[string]bool)}
+		}
+	}
+}
+
+func (c watchCommand) run(ctx context.Context, changed *changes) error {
+	flags, variables := sortedKeys(changed.flags), sortedKeys(changed.variables)
+	fmt.Fprintf(os.Stderr, "flags changed: %s\n", strings.Join(append(flags, variables...), ", "))
+
+	if c.Signal != "" {
+		if err := signalProcess(c.PidFile, signals[c.Signal]); err != nil {
+			return err
+		}
+	}
+
+	if c.Exec == "" {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Exec)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"SF_CHANGED_FLAGS="+strings.Join(flags, ","),
+		"SF_CHANGED_VARIABLES="+strings.Join(variables, ","),
+	)
+	return cmd.Run()
+}
+
+// signalProcess sends signal to process whose id is in pid file, file is read every time
+// so restarted process receives the signal too
+func signalProcess(pidFile string, sig syscall.Signal) error {
+	data, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		return err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("invalid pid file %s: %w", pidFile, err)
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(sig)
+}
+
+func sortedKeys(values map[string]bool) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	wc := watchCommand{}
+	_, err := parser.AddCommand(
+		"watch",
+		"Run hook when flags change",
+		"Run command or signal process when flags change, changed identifiers are passed in SF_CHANGED_FLAGS and SF_CHANGED_VARIABLES",
+		&wc,
+	)
+
+	if err != nil {
+		log.Printf("error adding command %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/antonmedv/expr"
+	"github.com/simpleflags/evaluation"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// whatIf evaluates target against current and proposed configuration of flag,
+// proposed configuration is never sent to the server
+func (c evaluateCommand) whatIf(target map[string]any) error {
+	if len(c.Args.Identifiers) != 1 {
+		return errors.New("provide exactly one flag identifier with --with-rule or --with-file")
+	}
+	identifier := c.Args.Identifiers[0]
+
+	s, err := c.configSnapshot()
+	if err != nil {
+		return err
+	}
+
+	current, ok := s.Flags[identifier]
+	if !ok {
+		return fmt.Errorf("flag %s not found", identifier)
+	}
+
+	proposed, err := c.proposedFlag(current)
+	if err != nil {
+		return err
+	}
+
+	if c.Explain {
+		fmt.Println("Current configuration")
+		printTrace(explainFlag(s, current, target))
+		fmt.Println("Proposed configuration")
+		printTrace(explainFlag(s, proposed, target))
+		return nil
+	}
+
+	currentValue, err := s.evaluateFlag(current, target)
+	if err != nil {
+		return fmt.Errorf("error evaluating current configuration: %w", err)
+	}
+	proposedValue, err := s.evaluateFlag(proposed, target)
+	if err != nil {
+		return fmt.Errorf("error evaluating proposed configuration: %w", err)
+	}
+
+	return printMatrix(c.Output, []string{"Flag", "Current", "Proposed"}, [][]string{{
+		identifier,
+		compactJSON(currentValue),
+		compactJSON(proposedValue),
+	}})
+}
+
+// proposedFlag applies configuration file and additional rules on top of current configuration
+func (c evaluateCommand) proposedFlag(current evaluation.Flag) (evaluation.Flag, error) {
+	proposed := current
+	proposed.Rules = append([]evaluation.Rule{}, current.Rules...)
+
+	if c.WithFile != "" {
+		data, err := ioutil.ReadFile(c.WithFile)
+		if err != nil {
+			return proposed, err
+		}
+		if err = unmarshalConfig(c.WithFile, data, &proposed); err != nil {
+			return proposed, fmt.Errorf("invalid flag file %s: %w", c.WithFile, err)
+		}
+	}
+
+	for _, rule := range c.WithRule {
+		i := strings.LastIndex(rule, "=")
+		if i <= 0 {
+			return proposed, fmt.Errorf("invalid rule %q, expected <expression>=<value>", rule)
+		}
+		proposed.Rules = append(proposed.Rules, evaluation.Rule{
+			Expression: strings.TrimSpace(rule[:i]),
+			Value:      ruleValue(strings.TrimSpace(rule[i+1:])),
+		})
+	}
+	proposed.Identifier = current.Identifier
+	return proposed, nil
+}
+
+// ruleValue converts rule value same way as flag --rule so proposed rules match saved ones
+func ruleValue(val string) any {
+	value, err := expr.Eval(val, nil)
+	if err != nil {
+		return val
+	}
+	return value
+}
+
+// unmarshalConfig decodes JSON or YAML file into value, YAML is converted to JSON first
+// so json tags of evaluation types apply to both formats
+func unmarshalConfig(file string, data []byte, value any) error {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		var content any
+		if err := yaml.Unmarshal(data, &content); err != nil {
+			return err
+		}
+		var err error
+		if data, err = json.Marshal(content); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, value)
+}